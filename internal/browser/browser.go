@@ -0,0 +1,41 @@
+package browser
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+)
+
+// Open opens link in the default browser. it does not wait for the browser to
+// exit. only http and https links are opened, anything else could be handed
+// to some other program by the opener
+func Open(link string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return fmt.Errorf("error while parsing link: %v", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("refusing to open link with scheme %q", u.Scheme)
+	}
+
+	cmd := openCommand(link)
+	if cmd == nil {
+		return fmt.Errorf("opening links is not supported on this platform")
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("error while running %s: %v", cmd.Path, err)
+	}
+
+	// reap the process once the opener has handed the link off
+	go func() {
+		err := cmd.Wait()
+		if err != nil {
+			slog.Warn("opener exited with error", "url", link, "error", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build linux
+
+package browser
+
+import "os/exec"
+
+func openCommand(url string) *exec.Cmd {
+	return exec.Command("xdg-open", url)
+}
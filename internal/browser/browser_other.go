@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package browser
+
+import "os/exec"
+
+func openCommand(url string) *exec.Cmd {
+	return nil
+}
@@ -0,0 +1,394 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+const configFilePath = "config.json"
+
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %v", err)
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %v", s, err)
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+// TimeOfDay is a wall clock time, written as "15:04". it is stored as the
+// time since midnight
+type TimeOfDay time.Duration
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	d := time.Duration(t)
+	return json.Marshal(fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60))
+}
+
+func (t *TimeOfDay) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("time of day must be a string: %v", err)
+	}
+
+	v, err := time.Parse("15:04", s)
+	if err != nil {
+		return fmt.Errorf("invalid time of day %q: %v", s, err)
+	}
+
+	*t = TimeOfDay(time.Duration(v.Hour())*time.Hour + time.Duration(v.Minute())*time.Minute)
+
+	return nil
+}
+
+type FollowUpConfig struct {
+	Enabled bool     `json:"enabled"`
+	After   Duration `json:"after"`
+
+	// Label (a label id) and Keyword limit follow ups to sent messages with
+	// the label or with the keyword in their subject. every sent message is
+	// followed up if both are empty
+	Label   string `json:"label"`
+	Keyword string `json:"keyword"`
+}
+
+// AliasConfig changes how messages addressed to one of the user's aliases or
+// groups are handled
+type AliasConfig struct {
+	Address string `json:"address"`
+
+	// Name is shown in notifications instead of the address
+	Name string `json:"name"`
+
+	// Mute keeps messages to the alias in the history without showing a
+	// notification
+	Mute bool `json:"mute"`
+}
+
+// VipConfig lists senders whose messages are shown as critical notifications
+// and shown again until they are read
+type VipConfig struct {
+	Addresses []string `json:"addresses"`
+	Domains   []string `json:"domains"`
+
+	// ContactGroups are the names or resource names (contactGroups/...) of
+	// google contact groups whose members are vips
+	ContactGroups []string `json:"contactGroups"`
+
+	// RenotifyIntervals are the delays before each repeated notification. the
+	// last interval is used for every notification after it
+	RenotifyIntervals []Duration `json:"renotifyIntervals"`
+	MaxRenotify       int        `json:"maxRenotify"`
+}
+
+// QuotaWindowConfig is the budget of gmail quota units for a rolling window
+type QuotaWindowConfig struct {
+	Duration Duration `json:"duration"`
+	Budget   int64    `json:"budget"`
+}
+
+type GmailConfig struct {
+	UpdateFreq       Duration `json:"updateFreq"`
+	FetchAttachments bool     `json:"fetchAttachments"`
+
+	// Labels are the ids of the labels to watch for new and unread messages
+	Labels            []string `json:"labels"`
+	UnreadRefreshFreq Duration `json:"unreadRefreshFreq"`
+
+	// OfflineAfter is the number of consecutive checks that fail because of
+	// the network before polling is replaced by connectivity probes
+	OfflineAfter int `json:"offlineAfter"`
+
+	FollowUps FollowUpConfig `json:"followUps"`
+
+	// Aliases configures addresses that are not send-as addresses (such as
+	// groups) and how messages to each alias are handled
+	Aliases []AliasConfig `json:"aliases"`
+
+	Vip VipConfig `json:"vip"`
+
+	// QuotaBudgets throttle polling when the quota units used in any window
+	// would exceed its budget. lower them when other tools share the same
+	// oauth client
+	QuotaBudgets []QuotaWindowConfig `json:"quotaBudgets"`
+}
+
+// Alias returns the config for the address, or a config with just the address
+// if there is none
+func (c *GmailConfig) Alias(address string) AliasConfig {
+	for _, a := range c.Aliases {
+		if strings.EqualFold(a.Address, address) {
+			return a
+		}
+	}
+
+	return AliasConfig{Address: address}
+}
+
+// CalendarDigestConfig configures the daily summary of the day's meetings
+type CalendarDigestConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// At is when the digest is shown, in the timezone of the account. it is
+	// also shown on the first start of a day if it has not been shown yet
+	At TimeOfDay `json:"at"`
+
+	// SkipEmpty skips the digest on days without events
+	SkipEmpty bool `json:"skipEmpty"`
+
+	// WorkdayStart and WorkdayEnd bound the free blocks listed in the digest
+	WorkdayStart TimeOfDay `json:"workdayStart"`
+	WorkdayEnd   TimeOfDay `json:"workdayEnd"`
+}
+
+// CalendarDndRuleConfig matches events that turn on do not disturb. every
+// condition that is set has to match
+type CalendarDndRuleConfig struct {
+	// Keyword matches events with the keyword in their summary
+	Keyword string `json:"keyword"`
+
+	// MinAttendees matches events with at least this many other attendees
+	MinAttendees int `json:"minAttendees"`
+
+	// BusyOnly limits the rule to events that show the user as busy
+	BusyOnly bool `json:"busyOnly"`
+}
+
+// CalendarDndConfig holds back non-urgent notifications during some events.
+// they are shown as a summary once the event ends
+type CalendarDndConfig struct {
+	Enabled     bool                    `json:"enabled"`
+	FocusTime   bool                    `json:"focusTime"`
+	OutOfOffice bool                    `json:"outOfOffice"`
+	Rules       []CalendarDndRuleConfig `json:"rules"`
+}
+
+// CalendarSelectionConfig picks what is shown for the events of a calendar.
+// calendars that are not selected are ignored
+type CalendarSelectionConfig struct {
+	// Id is the id of the calendar. "primary" is the user's own calendar
+	Id string `json:"id"`
+
+	// Remind shows the popup reminders of events
+	Remind bool `json:"remind"`
+
+	// NotifyChanges shows invitations, updates and cancellations
+	NotifyChanges bool `json:"notifyChanges"`
+}
+
+// UnmarshalJSON also accepts a plain calendar id, as calendars were listed
+// before they could be configured separately
+func (c *CalendarSelectionConfig) UnmarshalJSON(b []byte) error {
+	var id string
+	if err := json.Unmarshal(b, &id); err == nil {
+		*c = CalendarSelectionConfig{Id: id, Remind: true, NotifyChanges: true}
+		return nil
+	}
+
+	type plain CalendarSelectionConfig
+	return json.Unmarshal(b, (*plain)(c))
+}
+
+type CalendarConfig struct {
+	Enabled    bool     `json:"enabled"`
+	UpdateFreq Duration `json:"updateFreq"`
+
+	// Calendars are the calendars to watch
+	Calendars []CalendarSelectionConfig `json:"calendars"`
+
+	// LookAhead is how far ahead events are fetched
+	LookAhead Duration `json:"lookAhead"`
+
+	Digest CalendarDigestConfig `json:"digest"`
+	Dnd    CalendarDndConfig    `json:"dnd"`
+}
+
+type ChatConfig struct {
+	Enabled    bool     `json:"enabled"`
+	UpdateFreq Duration `json:"updateFreq"`
+
+	// MutedSpaces are the names (spaces/...) or display names of spaces that
+	// are never notified
+	MutedSpaces []string `json:"mutedSpaces"`
+
+	// CoalesceWindow is the shortest time between two notifications for the
+	// same space
+	CoalesceWindow Duration `json:"coalesceWindow"`
+}
+
+type DriveConfig struct {
+	Enabled    bool     `json:"enabled"`
+	UpdateFreq Duration `json:"updateFreq"`
+
+	// SharedWithMe notifies about files that are shared with the user
+	SharedWithMe bool `json:"sharedWithMe"`
+
+	// Comments notifies about comments that mention the user or assign them
+	// an action item
+	Comments bool `json:"comments"`
+}
+
+type TasksConfig struct {
+	Enabled    bool     `json:"enabled"`
+	UpdateFreq Duration `json:"updateFreq"`
+
+	// DueAt is the time of day tasks are due at on their due date
+	DueAt TimeOfDay `json:"dueAt"`
+
+	// Offsets are how long before a task is due that it is reminded about
+	Offsets []Duration `json:"offsets"`
+
+	// OverdueAt is the time of day overdue tasks are reminded about
+	OverdueAt TimeOfDay `json:"overdueAt"`
+}
+
+// AlertCenterConfig configures the security alerts of the workspace. they are
+// only watched if the account is an admin account
+type AlertCenterConfig struct {
+	Enabled    bool     `json:"enabled"`
+	UpdateFreq Duration `json:"updateFreq"`
+}
+
+type Config struct {
+	// Admin is set if the account is a workspace administrator, which is
+	// needed for the alert center
+	Admin bool `json:"admin"`
+
+	Gmail       GmailConfig       `json:"gmail"`
+	Calendar    CalendarConfig    `json:"calendar"`
+	Chat        ChatConfig        `json:"chat"`
+	Drive       DriveConfig       `json:"drive"`
+	Tasks       TasksConfig       `json:"tasks"`
+	AlertCenter AlertCenterConfig `json:"alertCenter"`
+}
+
+func Default() *Config {
+	return &Config{
+		Gmail: GmailConfig{
+			UpdateFreq:        Duration(time.Minute * 1),
+			FetchAttachments:  true,
+			Labels:            []string{"INBOX"},
+			UnreadRefreshFreq: Duration(time.Minute * 15),
+			OfflineAfter:      3,
+			FollowUps: FollowUpConfig{
+				Enabled: false,
+				After:   Duration(time.Hour * 72),
+			},
+			Vip: VipConfig{
+				RenotifyIntervals: []Duration{
+					Duration(time.Minute * 5),
+					Duration(time.Minute * 15),
+					Duration(time.Minute * 30),
+				},
+				MaxRenotify: 5,
+			},
+			// the per user limit of the gmail api
+			QuotaBudgets: []QuotaWindowConfig{
+				{Duration: Duration(time.Minute), Budget: 15000},
+			},
+		},
+		Calendar: CalendarConfig{
+			Enabled:    false,
+			UpdateFreq: Duration(time.Minute * 5),
+			Calendars:  []CalendarSelectionConfig{{Id: "primary", Remind: true, NotifyChanges: true}},
+			LookAhead:  Duration(time.Hour * 24 * 7),
+			Digest: CalendarDigestConfig{
+				Enabled:      true,
+				At:           TimeOfDay(time.Hour * 8),
+				WorkdayStart: TimeOfDay(time.Hour * 9),
+				WorkdayEnd:   TimeOfDay(time.Hour * 17),
+			},
+			Dnd: CalendarDndConfig{
+				Enabled:     true,
+				FocusTime:   true,
+				OutOfOffice: true,
+			},
+		},
+		Chat: ChatConfig{
+			Enabled:        false,
+			UpdateFreq:     Duration(time.Minute * 1),
+			CoalesceWindow: Duration(time.Minute * 2),
+		},
+		Drive: DriveConfig{
+			Enabled:      false,
+			UpdateFreq:   Duration(time.Minute * 2),
+			SharedWithMe: true,
+			Comments:     true,
+		},
+		Tasks: TasksConfig{
+			Enabled:    false,
+			UpdateFreq: Duration(time.Minute * 5),
+			DueAt:      TimeOfDay(time.Hour * 9),
+			Offsets:    []Duration{Duration(time.Hour * 24), 0},
+			OverdueAt:  TimeOfDay(time.Hour * 9),
+		},
+		AlertCenter: AlertCenterConfig{
+			Enabled:    true,
+			UpdateFreq: Duration(time.Minute * 5),
+		},
+	}
+}
+
+// Load reads the config file, falling back to the defaults for any missing
+// values. A missing config file is not an error.
+func Load() (*Config, error) {
+	cfg := Default()
+
+	f, err := os.Open(configFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("config file does not exist, using defaults", "file", configFilePath)
+		return cfg, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file (%s): %v", configFilePath, err)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file (%s): %v", configFilePath, err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes cfg to the config file, replacing it
+func Save(cfg *Config) error {
+	b, err := json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %v", err)
+	}
+
+	tmpPath := configFilePath + ".tmp"
+	err = os.WriteFile(tmpPath, append(b, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write config file (%s): %v", tmpPath, err)
+	}
+
+	err = os.Rename(tmpPath, configFilePath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace config file (%s): %v", configFilePath, err)
+	}
+
+	return nil
+}
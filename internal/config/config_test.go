@@ -0,0 +1,42 @@
+package config_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/link00000000/gwsn/internal/config"
+)
+
+func TestCalendarSelectionAcceptsPlainIds(t *testing.T) {
+	var cfg config.CalendarConfig
+	err := json.Unmarshal([]byte(`{"calendars": ["primary", {"id": "team", "notifyChanges": true}]}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []config.CalendarSelectionConfig{
+		{Id: "primary", Remind: true, NotifyChanges: true},
+		{Id: "team", NotifyChanges: true},
+	}
+
+	if len(cfg.Calendars) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, cfg.Calendars)
+	}
+
+	for i := range expected {
+		if cfg.Calendars[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], cfg.Calendars[i])
+		}
+	}
+}
+
+// sources that need more scopes than gmail are opt in, so that a default
+// config does not ask for access to them
+func TestDefaultDisablesOptionalSources(t *testing.T) {
+	cfg := config.Default()
+
+	if cfg.Calendar.Enabled || cfg.Chat.Enabled || cfg.Drive.Enabled || cfg.Tasks.Enabled {
+		t.Errorf("expected optional sources to be disabled, got calendar=%v chat=%v drive=%v tasks=%v",
+			cfg.Calendar.Enabled, cfg.Chat.Enabled, cfg.Drive.Enabled, cfg.Tasks.Enabled)
+	}
+}
@@ -17,12 +17,20 @@ func NewProcessor(t transport.Transport) *Processor {
 }
 
 func (p *Processor) Ping(ctx context.Context) error {
-	msg, err := makeTransportMsg(CmdType_Ping, PingPayload{})
+	return sendPayload(ctx, p.t, CmdType_Ping, PingPayload{})
+}
+
+func (p *Processor) RecvPing(ctx context.Context) (*PingPayload, error) {
+	return recvPayload[PingPayload](ctx, p.t, CmdType_Ping)
+}
+
+func sendPayload[T any](ctx context.Context, t transport.Transport, cmdType CmdType, payload T) error {
+	msg, err := makeTransportMsg(cmdType, payload)
 	if err != nil {
 		return fmt.Errorf("failed to make transport message: %v", err)
 	}
 
-	err = p.t.Send(ctx, msg)
+	err = t.Send(ctx, msg)
 	if err != nil {
 		return fmt.Errorf("failed to send message via transport: %v", err)
 	}
@@ -30,8 +38,29 @@ func (p *Processor) Ping(ctx context.Context) error {
 	return nil
 }
 
-func (p *Processor) RecvPing(ctx context.Context) (*PingPayload, error) {
+func recvPayload[T any](ctx context.Context, t transport.Transport, cmdType CmdType) (*T, error) {
+	tmsg, err := t.Recv(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive message via transport: %v", err)
+	}
+
+	msg := Msg{}
+	err = json.Unmarshal(*tmsg, &msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %v", err)
+	}
+
+	if msg.CmdType != cmdType {
+		return nil, fmt.Errorf("expected %s message, received %s", cmdType, msg.CmdType)
+	}
+
+	payload := new(T)
+	err = json.Unmarshal(msg.Payload, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %v", err)
+	}
 
+	return payload, nil
 }
 
 func makeTransportMsg[T any](cmdType CmdType, payload T) (*transport.Msg, error) {
@@ -0,0 +1,60 @@
+package dnd
+
+import "sync"
+
+// Deferred is a notification that was held back while do not disturb was on
+type Deferred struct {
+	Title string
+	Body  string
+}
+
+// Dnd holds back non-urgent notifications while do not disturb is on, so that
+// they can be released as a summary once it is turned off
+type Dnd struct {
+	mu       sync.Mutex
+	reason   string
+	deferred []Deferred
+}
+
+func New() *Dnd {
+	return &Dnd{}
+}
+
+// Reason is why do not disturb is on, or empty if it is off
+func (d *Dnd) Reason() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.reason
+}
+
+// Set turns do not disturb on for reason, or off if reason is empty. the
+// notifications deferred while it was on are returned when it is turned off
+func (d *Dnd) Set(reason string) []Deferred {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.reason = reason
+	if reason != "" {
+		return nil
+	}
+
+	released := d.deferred
+	d.deferred = nil
+
+	return released
+}
+
+// Defer holds back the notification if do not disturb is on and reports
+// whether it did
+func (d *Dnd) Defer(title, body string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.reason == "" {
+		return false
+	}
+
+	d.deferred = append(d.deferred, Deferred{Title: title, Body: body})
+	return true
+}
@@ -0,0 +1,35 @@
+package dnd_test
+
+import (
+	"testing"
+
+	"github.com/link00000000/gwsn/internal/dnd"
+)
+
+func TestDndReleasesDeferredWhenTurnedOff(t *testing.T) {
+	d := dnd.New()
+
+	if d.Defer("before", "") {
+		t.Errorf("expected notifications to be shown while do not disturb is off")
+	}
+
+	d.Set("Focus time: Deep work")
+
+	if !d.Defer("first", "") || !d.Defer("second", "") {
+		t.Fatalf("expected notifications to be deferred while do not disturb is on")
+	}
+
+	// changing the reason keeps what was deferred
+	if released := d.Set("In All hands"); len(released) != 0 {
+		t.Errorf("expected nothing to be released while still on, got %v", released)
+	}
+
+	released := d.Set("")
+	if len(released) != 2 || released[0].Title != "first" || released[1].Title != "second" {
+		t.Errorf("expected both deferred notifications to be released, got %v", released)
+	}
+
+	if released := d.Set(""); len(released) != 0 {
+		t.Errorf("expected deferred notifications to only be released once, got %v", released)
+	}
+}
@@ -0,0 +1,205 @@
+package gworkspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/api/alertcenter/v1beta1"
+)
+
+const alertsStateFilePath = "alerts_state.json"
+
+// alertConsoleUrl is the page of an alert in the admin console, without the
+// alert id
+const alertConsoleUrl = "https://admin.google.com/ac/ac/alerts/"
+
+const (
+	AlertSeverity_High   = "HIGH"
+	AlertSeverity_Medium = "MEDIUM"
+	AlertSeverity_Low    = "LOW"
+)
+
+type AlertsMonitorCfg struct {
+	UpdateFreq time.Duration
+}
+
+// Alert is an alert from the alert center of the workspace
+type Alert struct {
+	Id string
+
+	// Type is the kind of alert, e.g. "Suspicious login"
+	Type string
+
+	// Source is the product that raised the alert, e.g. "Google identity"
+	Source string
+
+	// Severity is one of the AlertSeverity_* constants, or empty if the alert
+	// has none
+	Severity string
+	Status   string
+
+	CreateTime time.Time
+
+	// Link opens the alert in the admin console
+	Link string
+}
+
+type alertsState struct {
+	// CreateTime is the create time of the newest alert that has been seen
+	CreateTime time.Time
+}
+
+// AlertsMonitor polls the alert center for new alerts. it needs an account
+// that is allowed to read alerts, i.e. an administrator
+type AlertsMonitor struct {
+	mu    sync.Mutex
+	svc   *alertcenter.Service
+	cfg   AlertsMonitorCfg
+	state *alertsState
+
+	alertsChan chan []*Alert
+}
+
+func NewAlertsMonitor(svc *alertcenter.Service, cfg AlertsMonitorCfg) (*AlertsMonitor, error) {
+	state := &alertsState{}
+	ok, err := readJsonFile(alertsStateFilePath, state)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading alerts state: %v", err)
+	}
+
+	if !ok {
+		state = nil
+	}
+
+	return &AlertsMonitor{
+		svc:        svc,
+		cfg:        cfg,
+		state:      state,
+		alertsChan: make(chan []*Alert, 32),
+	}, nil
+}
+
+// Alerts receives the alerts created since the last poll, oldest first.
+// alerts created before the very first poll are not sent
+func (a *AlertsMonitor) Alerts() <-chan []*Alert {
+	return a.alertsChan
+}
+
+func (a *AlertsMonitor) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.UpdateFreq)
+	defer ticker.Stop()
+
+	poll := func() {
+		err := a.Poll(ctx)
+		if err != nil {
+			slog.Error("error while polling alert center", "error", err)
+		}
+	}
+
+	poll()
+
+	for {
+		select {
+		case <-ticker.C:
+			poll()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Poll lists the alerts created after the newest alert that has been seen
+func (a *AlertsMonitor) Poll(ctx context.Context) error {
+	a.mu.Lock()
+	prev := a.state
+	a.mu.Unlock()
+
+	if prev == nil {
+		slog.Info("polling alert center for the first time, existing alerts will not be notified")
+
+		return a.saveState(&alertsState{CreateTime: time.Now().Round(0)})
+	}
+
+	alerts := make([]*Alert, 0)
+	newest := prev.CreateTime
+
+	err := a.svc.Alerts.List().
+		Filter(fmt.Sprintf("createTime > %q", prev.CreateTime.UTC().Format(time.RFC3339Nano))).
+		OrderBy("createTime asc").
+		Context(ctx).
+		Pages(ctx, func(res *alertcenter.ListAlertsResponse) error {
+			for _, al := range res.Alerts {
+				created, err := time.Parse(time.RFC3339Nano, al.CreateTime)
+				if err != nil {
+					slog.Warn("skipping alert with invalid create time", "alertId", al.AlertId, "error", err)
+					continue
+				}
+
+				if !created.After(prev.CreateTime) || al.Deleted {
+					continue
+				}
+
+				if created.After(newest) {
+					newest = created
+				}
+
+				alerts = append(alerts, newAlert(al, created))
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		return fmt.Errorf("error while listing alerts: %w", err)
+	}
+
+	err = a.saveState(&alertsState{CreateTime: newest})
+	if err != nil {
+		return err
+	}
+
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	select {
+	case a.alertsChan <- alerts:
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+func (a *AlertsMonitor) saveState(state *alertsState) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.state = state
+
+	err := writeJsonFile(alertsStateFilePath, state)
+	if err != nil {
+		return fmt.Errorf("error while saving alerts state: %v", err)
+	}
+
+	return nil
+}
+
+func newAlert(al *alertcenter.Alert, created time.Time) *Alert {
+	alert := &Alert{
+		Id:         al.AlertId,
+		Type:       al.Type,
+		Source:     al.Source,
+		CreateTime: created,
+		Link:       alertConsoleUrl + al.AlertId,
+	}
+
+	if al.Metadata != nil {
+		alert.Severity = al.Metadata.Severity
+		alert.Status = al.Metadata.Status
+	}
+
+	return alert
+}
@@ -0,0 +1,112 @@
+package gworkspace_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"google.golang.org/api/alertcenter/v1beta1"
+)
+
+// fakeAlertCenter serves alerts, filtered by the createTime in the filter of
+// the request
+type fakeAlertCenter struct {
+	alerts []*alertcenter.Alert
+}
+
+func newTestAlertCenterService(t *testing.T, f *fakeAlertCenter) *alertcenter.Service {
+	opts := gworkspace.FakeApi(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta1/alerts" {
+			http.NotFound(w, r)
+			return
+		}
+
+		filter := r.URL.Query().Get("filter")
+		since, err := time.Parse(time.RFC3339Nano, strings.Trim(strings.TrimPrefix(filter, "createTime > "), `"`))
+		if err != nil {
+			http.Error(w, "bad filter "+filter, http.StatusBadRequest)
+			return
+		}
+
+		alerts := slices.DeleteFunc(slices.Clone(f.alerts), func(a *alertcenter.Alert) bool {
+			created, _ := time.Parse(time.RFC3339Nano, a.CreateTime)
+			return !created.After(since)
+		})
+
+		json.NewEncoder(w).Encode(&alertcenter.ListAlertsResponse{Alerts: alerts})
+	})
+
+	svc, err := alertcenter.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return svc
+}
+
+func TestAlertsMonitorNotifiesNewAlerts(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	f := &fakeAlertCenter{alerts: []*alertcenter.Alert{
+		{AlertId: "old", Type: "Suspicious login", CreateTime: time.Now().Add(-time.Hour).Format(time.RFC3339Nano)},
+	}}
+
+	a, err := gworkspace.NewAlertsMonitor(newTestAlertCenterService(t, f), gworkspace.AlertsMonitorCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case alerts := <-a.Alerts():
+		t.Fatalf("expected the first poll to not notify, got %d alerts", len(alerts))
+	default:
+	}
+
+	at := time.Now().Add(time.Second)
+	f.alerts = append(f.alerts,
+		&alertcenter.Alert{AlertId: "login", Type: "Suspicious login", Source: "Google identity", CreateTime: at.Format(time.RFC3339Nano), Metadata: &alertcenter.AlertMetadata{Severity: gworkspace.AlertSeverity_High, Status: "NOT_STARTED"}},
+		&alertcenter.Alert{AlertId: "deleted", Type: "Device compromised", CreateTime: at.Format(time.RFC3339Nano), Deleted: true},
+	)
+
+	// a restarted monitor carries on from the saved create time
+	a, err = gworkspace.NewAlertsMonitor(newTestAlertCenterService(t, f), gworkspace.AlertsMonitorCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var alerts []*gworkspace.Alert
+	select {
+	case alerts = <-a.Alerts():
+	default:
+		t.Fatal("expected new alerts")
+	}
+
+	if len(alerts) != 1 || alerts[0].Id != "login" || alerts[0].Severity != gworkspace.AlertSeverity_High || alerts[0].Link != gworkspace.AlertConsoleUrl+"login" {
+		t.Fatalf("unexpected alerts %+v", alerts)
+	}
+
+	err = a.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case alerts := <-a.Alerts():
+		t.Fatalf("expected alerts to be notified once, got %d alerts", len(alerts))
+	default:
+	}
+}
@@ -0,0 +1,655 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+const calendarFiredFilePath = "calendar_reminders.json"
+
+// how often scheduled reminders are checked. reminder times are compared
+// against the wall clock so a reminder that fell due while suspended fires on
+// the first check after resuming
+const calendarReminderCheckFreq = time.Second * 15
+
+const (
+	CalendarResponse_Accepted    = "accepted"
+	CalendarResponse_Declined    = "declined"
+	CalendarResponse_Tentative   = "tentative"
+	CalendarResponse_NeedsAction = "needsAction"
+)
+
+type CalendarEvent struct {
+	Id         string
+	CalendarId string
+
+	Summary  string
+	Location string
+	HtmlLink string
+
+	// Status is confirmed or tentative. cancelled events are never kept
+	Status string
+
+	// EventType is one of the CalendarEventType values
+	EventType string
+
+	// Transparent is set for events that do not show the user as busy
+	Transparent bool
+
+	// ConferenceLink is the link to join the video call of the event, if it
+	// has one
+	ConferenceLink string
+
+	// Organizer is the name (or address) of the organizer. IsOrganizer is set
+	// if that is the user
+	Organizer   string
+	IsOrganizer bool
+
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+
+	// Attendees are the names (or addresses if they have no name) of the
+	// other people invited to the event. rooms and the user are left out
+	Attendees []string
+
+	// ResponseStatus is the user's response to the invitation, or empty if
+	// the user was not invited (e.g. the event is on their own calendar)
+	ResponseStatus string
+
+	// ReminderMinutes are the popup reminders of the event, in minutes before
+	// it starts
+	ReminderMinutes []int64
+}
+
+type CalendarReminder struct {
+	Event *CalendarEvent
+
+	// At is when the reminder was scheduled for
+	At time.Time
+}
+
+// CalendarSelection picks what is sent for the events of a watched calendar
+type CalendarSelection struct {
+	// Id is the id of the calendar. "primary" is the user's own calendar
+	Id string
+
+	// Remind sends the popup reminders of events
+	Remind bool
+
+	// NotifyChanges sends the changes to events
+	NotifyChanges bool
+}
+
+type CalendarMonitorCfg struct {
+	UpdateFreq time.Duration
+
+	// Calendars are the calendars to watch. they can be changed later with
+	// SetCalendars
+	Calendars []CalendarSelection
+
+	// LookAhead is how far ahead events are fetched. reminders set further
+	// ahead than this fire late, once the event comes into range
+	LookAhead time.Duration
+
+	Digest CalendarDigestCfg
+	Dnd    CalendarDndCfg
+}
+
+type calendarScheduledReminder struct {
+	key string
+	CalendarReminder
+}
+
+// CalendarMonitor keeps the upcoming events of the selected calendars in sync
+// and sends a reminder for each popup reminder of an event. the events and
+// sync tokens are persisted so that only changes are fetched, even after a
+// restart
+type CalendarMonitor struct {
+	mu  sync.Mutex
+	svc *calendar.Service
+	cfg CalendarMonitorCfg
+
+	state     map[string]*calendarSyncState
+	scheduled []*calendarScheduledReminder
+
+	// fired holds the keys of reminders that have already been sent, with the
+	// start of their event, so that they are not sent again when events are
+	// fetched again or after a restart
+	fired map[string]time.Time
+
+	// location is the timezone of the account, once it has been fetched
+	location *time.Location
+
+	// conflicts are the conflicts that were already found, with when they are
+	// over. nil until the first check if none were saved
+	conflicts map[string]time.Time
+
+	remindersChan chan []*CalendarReminder
+	changesChan   chan []*CalendarEventChange
+	digestsChan   chan *CalendarAgenda
+	conflictsChan chan []CalendarConflict
+
+	// refreshChan asks Watch to refresh straight away
+	refreshChan chan struct{}
+}
+
+func NewCalendarMonitor(svc *calendar.Service, cfg CalendarMonitorCfg) (*CalendarMonitor, error) {
+	state := make(map[string]*calendarSyncState)
+	_, err := readJsonFile(calendarSyncFilePath, &state)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading calendar sync state: %v", err)
+	}
+
+	fired := make(map[string]time.Time)
+	_, err = readJsonFile(calendarFiredFilePath, &fired)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading fired calendar reminders: %v", err)
+	}
+
+	if fired == nil {
+		fired = make(map[string]time.Time)
+	}
+
+	c := &CalendarMonitor{
+		svc:           svc,
+		cfg:           cfg,
+		state:         make(map[string]*calendarSyncState),
+		fired:         fired,
+		remindersChan: make(chan []*CalendarReminder, 32),
+		changesChan:   make(chan []*CalendarEventChange, 32),
+		digestsChan:   make(chan *CalendarAgenda, 1),
+		conflictsChan: make(chan []CalendarConflict, 32),
+		refreshChan:   make(chan struct{}, 1),
+	}
+
+	conflicts := make(map[string]time.Time)
+	ok, err := readJsonFile(calendarConflictsFilePath, &conflicts)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading calendar conflicts: %v", err)
+	}
+
+	if ok {
+		c.conflicts = conflicts
+	}
+
+	// calendars that are no longer watched are dropped
+	for _, cal := range cfg.Calendars {
+		if s, ok := state[cal.Id]; ok {
+			c.state[cal.Id] = s
+
+			for _, ev := range s.Events {
+				c.scheduled = append(c.scheduled, scheduleCalendarReminders(ev)...)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// SetCalendars replaces the watched calendars. the events of calendars that
+// are no longer watched are dropped and new calendars are synced straight away
+func (c *CalendarMonitor) SetCalendars(cals []CalendarSelection) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cfg.Calendars = slices.Clone(cals)
+
+	for id := range c.state {
+		if _, ok := c.selection(id); !ok {
+			delete(c.state, id)
+		}
+	}
+
+	c.scheduled = slices.DeleteFunc(c.scheduled, func(r *calendarScheduledReminder) bool {
+		_, ok := c.selection(r.Event.CalendarId)
+		return !ok
+	})
+
+	select {
+	case c.refreshChan <- struct{}{}:
+	default:
+	}
+
+	err := c.save()
+	if err != nil {
+		return fmt.Errorf("error while saving calendar sync state: %v", err)
+	}
+
+	return nil
+}
+
+// Calendars returns the watched calendars
+func (c *CalendarMonitor) Calendars() []CalendarSelection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.cfg.Calendars)
+}
+
+// CalendarListEntry is a calendar on the user's calendar list, including
+// shared and secondary calendars
+type CalendarListEntry struct {
+	Id   string
+	Name string
+
+	// Primary is set for the user's own calendar, which can also be watched
+	// as "primary"
+	Primary bool
+
+	// AccessRole is the user's access to the calendar, e.g. owner or reader
+	AccessRole string
+}
+
+// ListCalendars returns the calendars on the user's calendar list, whether
+// they are watched or not
+func (c *CalendarMonitor) ListCalendars(ctx context.Context) ([]CalendarListEntry, error) {
+	entries := make([]CalendarListEntry, 0)
+
+	err := c.svc.CalendarList.List().Pages(ctx, func(res *calendar.CalendarList) error {
+		for _, item := range res.Items {
+			name := item.SummaryOverride
+			if name == "" {
+				name = item.Summary
+			}
+
+			entries = append(entries, CalendarListEntry{
+				Id:         item.Id,
+				Name:       name,
+				Primary:    item.Primary,
+				AccessRole: item.AccessRole,
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error while listing calendars: %w", err)
+	}
+
+	return entries, nil
+}
+
+// selection must be called with c.mu held
+func (c *CalendarMonitor) selection(calendarId string) (CalendarSelection, bool) {
+	i := slices.IndexFunc(c.cfg.Calendars, func(cal CalendarSelection) bool { return cal.Id == calendarId })
+	if i < 0 {
+		return CalendarSelection{}, false
+	}
+
+	return c.cfg.Calendars[i], true
+}
+
+// Reminders receives reminders once they are due
+func (c *CalendarMonitor) Reminders() <-chan []*CalendarReminder {
+	return c.remindersChan
+}
+
+// Changes receives the events that were added, updated or cancelled since the
+// last sync. the events found by the very first sync of a calendar are not
+// changes
+func (c *CalendarMonitor) Changes() <-chan []*CalendarEventChange {
+	return c.changesChan
+}
+
+// NextEvent returns the meeting that is in progress or starts next, or nil if
+// there is none in the look ahead. all day and declined events are skipped
+func (c *CalendarMonitor) NextEvent(now time.Time) *CalendarEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var next *CalendarEvent
+	for _, s := range c.state {
+		for _, ev := range s.Events {
+			if ev.AllDay || ev.ResponseStatus == CalendarResponse_Declined || !ev.End.After(now) {
+				continue
+			}
+
+			if next == nil || ev.Start.Before(next.Start) || (ev.Start.Equal(next.Start) && calendarEventKey(ev) < calendarEventKey(next)) {
+				next = ev
+			}
+		}
+	}
+
+	return next
+}
+
+func (c *CalendarMonitor) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.UpdateFreq)
+	defer ticker.Stop()
+
+	reminderTicker := time.NewTicker(calendarReminderCheckFreq)
+	defer reminderTicker.Stop()
+
+	refresh := func() error {
+		c.mu.Lock()
+		loaded := c.location != nil
+		c.mu.Unlock()
+
+		if !loaded {
+			err := c.loadLocation(ctx)
+			if err != nil {
+				slog.Warn("error while fetching calendar timezone, using local timezone", "error", err)
+			}
+		}
+
+		err := c.Refresh(ctx)
+		if err != nil {
+			slog.Error("error while fetching calendar events", "error", err)
+		}
+
+		return err
+	}
+
+	err := refresh()
+	c.checkDue(ctx)
+
+	// the digest is only sent on startup if the events are up to date.
+	// otherwise it waits for its time of day
+	c.checkDigest(ctx, err == nil)
+
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-c.refreshChan:
+			refresh()
+			c.checkDue(ctx)
+		case <-reminderTicker.C:
+			c.checkDue(ctx)
+			c.checkDigest(ctx, false)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Refresh syncs the events of every watched calendar and reschedules the
+// reminders of the events that changed
+func (c *CalendarMonitor) Refresh(ctx context.Context) error {
+	now := time.Now()
+
+	c.mu.Lock()
+	cals := slices.Clone(c.cfg.Calendars)
+	prev := make(map[string]*calendarSyncState, len(c.state))
+	for id, s := range c.state {
+		prev[id] = s
+	}
+	c.mu.Unlock()
+
+	var errs error
+	state := make(map[string]*calendarSyncState, len(cals))
+	changes := make([]*CalendarEventChange, 0)
+	for _, cal := range cals {
+		s, ch, err := c.sync(ctx, cal.Id, prev[cal.Id], now)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("error while syncing calendar (calendar id = %s): %w", cal.Id, err))
+			state[cal.Id] = prev[cal.Id]
+			continue
+		}
+
+		state[cal.Id] = s
+		if cal.NotifyChanges {
+			changes = append(changes, ch...)
+		}
+	}
+
+	slog.Debug("synced calendars", "numChanges", len(changes))
+
+	c.mu.Lock()
+
+	// calendars may have been deselected while syncing
+	c.state = make(map[string]*calendarSyncState, len(state))
+	for id, s := range state {
+		if _, ok := c.selection(id); ok && s != nil {
+			c.state[id] = s
+		}
+	}
+
+	c.reschedule(prev, state)
+
+	err := c.save()
+	if err != nil {
+		errs = errors.Join(errs, fmt.Errorf("error while saving calendar sync state: %v", err))
+	}
+
+	c.mu.Unlock()
+
+	if len(changes) > 0 {
+		select {
+		case c.changesChan <- changes:
+		case <-ctx.Done():
+		}
+	}
+
+	err = c.checkConflicts(ctx, now)
+	if err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	return errs
+}
+
+// reschedule replaces the reminders of every event that differs between prev
+// and state. must be called with c.mu held
+func (c *CalendarMonitor) reschedule(prev, state map[string]*calendarSyncState) {
+	changed := make(map[string]*CalendarEvent)
+	removed := make(map[string]struct{})
+
+	for id, s := range state {
+		if s == nil {
+			continue
+		}
+
+		var old map[string]*CalendarEvent
+		if p := prev[id]; p != nil {
+			old = p.Events
+		}
+
+		for evId, ev := range s.Events {
+			if o, ok := old[evId]; !ok || !o.Equal(ev) {
+				changed[calendarEventKey(ev)] = ev
+			}
+		}
+
+		for evId, o := range old {
+			if _, ok := s.Events[evId]; !ok {
+				removed[calendarEventKey(o)] = struct{}{}
+			}
+		}
+	}
+
+	c.scheduled = slices.DeleteFunc(c.scheduled, func(r *calendarScheduledReminder) bool {
+		key := calendarEventKey(r.Event)
+
+		_, isChanged := changed[key]
+		_, isRemoved := removed[key]
+
+		return isChanged || isRemoved
+	})
+
+	for _, ev := range changed {
+		c.scheduled = append(c.scheduled, scheduleCalendarReminders(ev)...)
+	}
+}
+
+// save must be called with c.mu held
+func (c *CalendarMonitor) save() error {
+	return writeJsonFile(calendarSyncFilePath, c.state)
+}
+
+func calendarEventKey(ev *CalendarEvent) string {
+	return ev.CalendarId + "/" + ev.Id
+}
+
+func (c *CalendarMonitor) checkDue(ctx context.Context) {
+	c.mu.Lock()
+
+	now := time.Now()
+	due := make([]*CalendarReminder, 0)
+	for _, r := range c.scheduled {
+		if _, ok := c.fired[r.key]; ok {
+			continue
+		}
+
+		if cal, ok := c.selection(r.Event.CalendarId); !ok || !cal.Remind {
+			continue
+		}
+
+		// a reminder that was missed (e.g. while the app was not running)
+		// is still useful until the event starts
+		if now.Before(r.At) || !now.Before(r.Event.Start) {
+			continue
+		}
+
+		c.fired[r.key] = r.Event.Start
+		due = append(due, &r.CalendarReminder)
+	}
+
+	changed := len(due) > 0
+	for key, start := range c.fired {
+		if now.After(start) {
+			delete(c.fired, key)
+			changed = true
+		}
+	}
+
+	var err error
+	if changed {
+		err = writeJsonFile(calendarFiredFilePath, c.fired)
+	}
+
+	c.mu.Unlock()
+
+	if err != nil {
+		slog.Error("error while saving fired calendar reminders", "error", err)
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	slog.Info("calendar reminders are due", "numReminders", len(due))
+
+	select {
+	case c.remindersChan <- due:
+	case <-ctx.Done():
+	}
+}
+
+func scheduleCalendarReminders(ev *CalendarEvent) []*calendarScheduledReminder {
+	if ev.ResponseStatus == CalendarResponse_Declined {
+		return nil
+	}
+
+	scheduled := make([]*calendarScheduledReminder, 0, len(ev.ReminderMinutes))
+	for _, m := range ev.ReminderMinutes {
+		scheduled = append(scheduled, &calendarScheduledReminder{
+			key: fmt.Sprintf("%s/%d/%d", calendarEventKey(ev), ev.Start.Unix(), m),
+			CalendarReminder: CalendarReminder{
+				Event: ev,
+				At:    ev.Start.Add(-time.Duration(m) * time.Minute),
+			},
+		})
+	}
+
+	return scheduled
+}
+
+// newCalendarEvent converts an event from the api. defaults are the popup
+// reminders of the calendar, used if the event does not override them
+func newCalendarEvent(calendarId string, item *calendar.Event, defaults []int64) (*CalendarEvent, error) {
+	ev := &CalendarEvent{
+		Id:          item.Id,
+		CalendarId:  calendarId,
+		Summary:     item.Summary,
+		Location:    item.Location,
+		HtmlLink:    item.HtmlLink,
+		Status:      item.Status,
+		EventType:   item.EventType,
+		Transparent: item.Transparency == "transparent",
+	}
+
+	if item.Organizer != nil {
+		ev.Organizer = item.Organizer.DisplayName
+		if ev.Organizer == "" {
+			ev.Organizer = item.Organizer.Email
+		}
+
+		ev.IsOrganizer = item.Organizer.Self
+	}
+
+	ev.ConferenceLink = calendarConferenceLink(item)
+
+	var err error
+	ev.Start, ev.AllDay, err = parseCalendarTime(item.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time: %v", err)
+	}
+
+	ev.End, _, err = parseCalendarTime(item.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end time: %v", err)
+	}
+
+	for _, a := range item.Attendees {
+		if a.Self {
+			ev.ResponseStatus = a.ResponseStatus
+			continue
+		}
+
+		if a.Resource {
+			continue
+		}
+
+		name := a.DisplayName
+		if name == "" {
+			name = a.Email
+		}
+
+		ev.Attendees = append(ev.Attendees, name)
+	}
+
+	if item.Reminders == nil || item.Reminders.UseDefault {
+		ev.ReminderMinutes = slices.Clone(defaults)
+	} else {
+		ev.ReminderMinutes = popupReminderMinutes(item.Reminders.Overrides)
+	}
+
+	return ev, nil
+}
+
+func popupReminderMinutes(reminders []*calendar.EventReminder) []int64 {
+	minutes := make([]int64, 0, len(reminders))
+	for _, r := range reminders {
+		if r.Method == "popup" {
+			minutes = append(minutes, r.Minutes)
+		}
+	}
+
+	return minutes
+}
+
+// parseCalendarTime returns the time of t and whether it is a whole day. whole
+// days start at midnight local time
+func parseCalendarTime(t *calendar.EventDateTime) (time.Time, bool, error) {
+	if t == nil {
+		return time.Time{}, false, fmt.Errorf("missing time")
+	}
+
+	if t.DateTime != "" {
+		v, err := time.Parse(time.RFC3339, t.DateTime)
+		return v, false, err
+	}
+
+	v, err := time.ParseInLocation(time.DateOnly, t.Date, time.Local)
+	return v, true, err
+}
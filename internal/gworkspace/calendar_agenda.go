@@ -0,0 +1,227 @@
+package gworkspace
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const calendarDigestFilePath = "calendar_digest.json"
+
+// free time shorter than this is not worth listing
+const calendarMinFreeBlock = time.Minute * 15
+
+type CalendarDigestCfg struct {
+	Enabled bool
+
+	// At is the time since midnight, in the timezone of the account, that the
+	// digest is sent at
+	At time.Duration
+
+	// SkipEmpty skips the digest on days without events
+	SkipEmpty bool
+
+	// WorkdayStart and WorkdayEnd are the times since midnight that bound the
+	// free blocks of the agenda
+	WorkdayStart time.Duration
+	WorkdayEnd   time.Duration
+}
+
+type CalendarTimeBlock struct {
+	Start time.Time
+	End   time.Time
+}
+
+// CalendarAgenda summarises what is left of a day
+type CalendarAgenda struct {
+	// Day is midnight at the start of the day, in the timezone of the account
+	Day time.Time
+
+	// Events are the events of the day that were not declined, ordered by
+	// start time. all day events come first
+	Events []*CalendarEvent
+
+	// Free are the gaps between meetings within the workday, from now on
+	Free []CalendarTimeBlock
+
+	Conflicts []CalendarConflict
+}
+
+type calendarDigestState struct {
+	// LastDay is the day the last digest was sent for, as a date in the
+	// timezone of the account
+	LastDay string
+}
+
+// Digests receives the agenda of the day once a day
+func (c *CalendarMonitor) Digests() <-chan *CalendarAgenda {
+	return c.digestsChan
+}
+
+// Location is the timezone of the account, or the local timezone if it has not
+// been fetched
+func (c *CalendarMonitor) Location() *time.Location {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.location == nil {
+		return time.Local
+	}
+
+	return c.location
+}
+
+// Agenda returns the agenda of the day of now, in the timezone of the account
+func (c *CalendarMonitor) Agenda(now time.Time) *CalendarAgenda {
+	loc := c.Location()
+
+	c.mu.Lock()
+	events := make([]*CalendarEvent, 0)
+	for _, s := range c.state {
+		for _, ev := range s.Events {
+			events = append(events, ev)
+		}
+	}
+	primary := c.primaryCalendarIds()
+	c.mu.Unlock()
+
+	return buildCalendarAgenda(events, primary, now.In(loc), c.cfg.Digest.WorkdayStart, c.cfg.Digest.WorkdayEnd)
+}
+
+// loadLocation fetches the timezone of the account. the timezone of the
+// machine is used if it cannot be fetched
+func (c *CalendarMonitor) loadLocation(ctx context.Context) error {
+	setting, err := c.svc.Settings.Get("timezone").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error while fetching timezone setting: %w", err)
+	}
+
+	loc, err := time.LoadLocation(setting.Value)
+	if err != nil {
+		return fmt.Errorf("error while loading timezone (timezone = %s): %v", setting.Value, err)
+	}
+
+	c.mu.Lock()
+	c.location = loc
+	c.mu.Unlock()
+
+	return nil
+}
+
+// checkDigest sends the digest once the time of day for it has passed, or
+// straight away on startup, unless it was already sent today
+func (c *CalendarMonitor) checkDigest(ctx context.Context, startup bool) {
+	if !c.cfg.Digest.Enabled {
+		return
+	}
+
+	now := time.Now().In(c.Location())
+	today := now.Format(time.DateOnly)
+
+	var state calendarDigestState
+	_, err := readJsonFile(calendarDigestFilePath, &state)
+	if err != nil {
+		slog.Error("error while reading calendar digest state", "error", err)
+		return
+	}
+
+	if state.LastDay == today {
+		return
+	}
+
+	if !startup && now.Before(startOfDay(now).Add(c.cfg.Digest.At)) {
+		return
+	}
+
+	state.LastDay = today
+	err = writeJsonFile(calendarDigestFilePath, state)
+	if err != nil {
+		slog.Error("error while saving calendar digest state", "error", err)
+		return
+	}
+
+	agenda := c.Agenda(now)
+	if c.cfg.Digest.SkipEmpty && len(agenda.Events) == 0 {
+		slog.Info("skipping calendar digest of a day without events", "day", today)
+		return
+	}
+
+	select {
+	case c.digestsChan <- agenda:
+	case <-ctx.Done():
+	}
+}
+
+// buildCalendarAgenda builds the agenda of the day of now, in the timezone of
+// now. events that are over are left out. primary holds the ids of the user's
+// own calendar
+func buildCalendarAgenda(events []*CalendarEvent, primary map[string]bool, now time.Time, workdayStart, workdayEnd time.Duration) *CalendarAgenda {
+	day := startOfDay(now)
+	end := day.AddDate(0, 0, 1)
+
+	agenda := &CalendarAgenda{Day: day}
+	for _, ev := range events {
+		if ev.ResponseStatus == CalendarResponse_Declined || !ev.End.After(now) || !ev.Start.Before(end) {
+			continue
+		}
+
+		agenda.Events = append(agenda.Events, ev)
+	}
+
+	slices.SortFunc(agenda.Events, func(a, b *CalendarEvent) int {
+		if a.AllDay != b.AllDay {
+			if a.AllDay {
+				return -1
+			}
+
+			return 1
+		}
+
+		return cmp.Or(a.Start.Compare(b.Start), a.End.Compare(b.End), cmp.Compare(calendarEventKey(a), calendarEventKey(b)))
+	})
+
+	meetings := slices.DeleteFunc(slices.Clone(agenda.Events), func(ev *CalendarEvent) bool { return ev.AllDay })
+
+	agenda.Conflicts = calendarConflicts(agenda.Events, primary)
+
+	cursor := day.Add(workdayStart)
+	if now.After(cursor) {
+		cursor = now
+	}
+
+	workEnd := day.Add(workdayEnd)
+	for _, ev := range meetings {
+		if ev.Start.After(cursor) {
+			agenda.Free = appendCalendarFreeBlock(agenda.Free, cursor, ev.Start, workEnd)
+		}
+
+		if ev.End.After(cursor) {
+			cursor = ev.End
+		}
+	}
+
+	agenda.Free = appendCalendarFreeBlock(agenda.Free, cursor, workEnd, workEnd)
+
+	return agenda
+}
+
+// appendCalendarFreeBlock appends the block from start to end, cut off at
+// limit, if it is long enough to be useful
+func appendCalendarFreeBlock(blocks []CalendarTimeBlock, start, end, limit time.Time) []CalendarTimeBlock {
+	if end.After(limit) {
+		end = limit
+	}
+
+	if end.Sub(start) < calendarMinFreeBlock {
+		return blocks
+	}
+
+	return append(blocks, CalendarTimeBlock{Start: start, End: end})
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
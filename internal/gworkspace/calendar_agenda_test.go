@@ -0,0 +1,101 @@
+package gworkspace
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildCalendarAgenda(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+
+	at := func(hour, min int) time.Time { return time.Date(2025, time.March, 3, hour, min, 0, 0, loc) }
+	meeting := func(id string, start, end time.Time) *CalendarEvent {
+		return &CalendarEvent{Id: id, CalendarId: "primary", Start: start, End: end}
+	}
+
+	events := []*CalendarEvent{
+		meeting("lunch", at(12, 0), at(13, 0)),
+		meeting("standup", at(9, 0), at(9, 15)),
+		meeting("review", at(12, 30), at(13, 30)),
+		meeting("short-gap", at(9, 20), at(10, 0)),
+		meeting("over", at(7, 0), at(7, 30)),
+		meeting("tomorrow", at(33, 0), at(34, 0)),
+		{Id: "holiday", Start: at(0, 0), End: at(24, 0), AllDay: true},
+		{Id: "declined", Start: at(15, 0), End: at(16, 0), ResponseStatus: CalendarResponse_Declined},
+	}
+
+	agenda := buildCalendarAgenda(events, map[string]bool{"primary": true}, at(8, 0), time.Hour*9, time.Hour*17)
+
+	ids := make([]string, len(agenda.Events))
+	for i, ev := range agenda.Events {
+		ids[i] = ev.Id
+	}
+
+	expectedIds := []string{"holiday", "standup", "short-gap", "lunch", "review"}
+	if len(ids) != len(expectedIds) {
+		t.Fatalf("expected events %v, got %v", expectedIds, ids)
+	}
+
+	for i := range ids {
+		if ids[i] != expectedIds[i] {
+			t.Fatalf("expected events %v, got %v", expectedIds, ids)
+		}
+	}
+
+	expectedFree := []CalendarTimeBlock{
+		{Start: at(10, 0), End: at(12, 0)},
+		{Start: at(13, 30), End: at(17, 0)},
+	}
+
+	if len(agenda.Free) != len(expectedFree) {
+		t.Fatalf("expected free blocks %v, got %v", expectedFree, agenda.Free)
+	}
+
+	for i, b := range agenda.Free {
+		if !b.Start.Equal(expectedFree[i].Start) || !b.End.Equal(expectedFree[i].End) {
+			t.Errorf("expected free block %v, got %v", expectedFree[i], b)
+		}
+	}
+
+	if len(agenda.Conflicts) != 1 || agenda.Conflicts[0].A.Id != "lunch" || agenda.Conflicts[0].B.Id != "review" {
+		t.Errorf("expected lunch to conflict with review, got %+v", agenda.Conflicts)
+	}
+
+	// free time before now is left out
+	agenda = buildCalendarAgenda(events, map[string]bool{"primary": true}, at(14, 0), time.Hour*9, time.Hour*17)
+	if len(agenda.Free) != 1 || !agenda.Free[0].Start.Equal(at(14, 0)) {
+		t.Errorf("expected free time from now, got %v", agenda.Free)
+	}
+}
+
+func TestCalendarMonitorDigestOncePerDay(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	c, err := NewCalendarMonitor(nil, CalendarMonitorCfg{Digest: CalendarDigestCfg{Enabled: true, At: time.Hour * 24}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not due until the end of the day, except on startup
+	c.checkDigest(t.Context(), false)
+
+	select {
+	case <-c.Digests():
+		t.Fatalf("expected no digest before its time")
+	default:
+	}
+
+	c.checkDigest(t.Context(), true)
+	<-c.Digests()
+
+	c.checkDigest(t.Context(), true)
+
+	select {
+	case <-c.Digests():
+		t.Errorf("expected only one digest a day")
+	default:
+	}
+}
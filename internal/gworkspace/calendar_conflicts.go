@@ -0,0 +1,162 @@
+package gworkspace
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const calendarConflictsFilePath = "calendar_conflicts.json"
+
+// CalendarConflict is a pair of meetings the user is going to that overlap. A
+// starts first
+type CalendarConflict struct {
+	A *CalendarEvent
+	B *CalendarEvent
+}
+
+func (c *CalendarConflict) key() string {
+	return fmt.Sprintf("%s/%d|%s/%d", calendarEventKey(c.A), c.A.Start.Unix(), calendarEventKey(c.B), c.B.Start.Unix())
+}
+
+// Conflicts receives the conflicts that appeared since the last sync. the
+// conflicts found when the monitor runs for the first time are not reported
+func (c *CalendarMonitor) Conflicts() <-chan []CalendarConflict {
+	return c.conflictsChan
+}
+
+// CalendarName is the name the user gave the calendar, or its id if it is not
+// known
+func (c *CalendarMonitor) CalendarName(calendarId string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s := c.state[calendarId]; s != nil && s.Name != "" {
+		return s.Name
+	}
+
+	return calendarId
+}
+
+// checkConflicts looks for conflicts between the upcoming events of every
+// watched calendar and sends the ones that were not there before
+func (c *CalendarMonitor) checkConflicts(ctx context.Context, now time.Time) error {
+	c.mu.Lock()
+
+	events := make([]*CalendarEvent, 0)
+	for _, s := range c.state {
+		for _, ev := range s.Events {
+			if ev.End.After(now) {
+				events = append(events, ev)
+			}
+		}
+	}
+
+	slices.SortFunc(events, func(a, b *CalendarEvent) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(calendarEventKey(a), calendarEventKey(b)))
+	})
+
+	conflicts := calendarConflicts(events, c.primaryCalendarIds())
+
+	known := make(map[string]time.Time, len(conflicts))
+	added := make([]CalendarConflict, 0)
+	for _, conflict := range conflicts {
+		key := conflict.key()
+		if _, ok := c.conflicts[key]; !ok {
+			added = append(added, conflict)
+		}
+
+		known[key] = conflict.A.End
+		if conflict.B.End.After(conflict.A.End) {
+			known[key] = conflict.B.End
+		}
+	}
+
+	// conflicts are remembered until both events are over, so that one that
+	// goes away and comes back (e.g. an event is declined and then accepted
+	// again) is reported again
+	first := c.conflicts == nil
+	c.conflicts = known
+	err := writeJsonFile(calendarConflictsFilePath, c.conflicts)
+
+	c.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("error while saving calendar conflicts: %v", err)
+	}
+
+	if first || len(added) == 0 {
+		return nil
+	}
+
+	slog.Info("found new calendar conflicts", "numConflicts", len(added))
+
+	select {
+	case c.conflictsChan <- added:
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+// primaryCalendarIds returns the ids the user's own calendar is watched as.
+// must be called with c.mu held
+func (c *CalendarMonitor) primaryCalendarIds() map[string]bool {
+	ids := map[string]bool{"primary": true}
+	for id, s := range c.state {
+		if s.Primary {
+			ids[id] = true
+		}
+	}
+
+	return ids
+}
+
+// calendarConflicts returns every pair of the events that overlap and that the
+// user is going to. events must be ordered by start time. primary holds the
+// ids of the user's own calendar
+func calendarConflicts(events []*CalendarEvent, primary map[string]bool) []CalendarConflict {
+	candidates := slices.DeleteFunc(slices.Clone(events), func(ev *CalendarEvent) bool {
+		return !isCalendarConflictCandidate(ev, primary[ev.CalendarId])
+	})
+
+	var conflicts []CalendarConflict
+	for i, a := range candidates {
+		for _, b := range candidates[i+1:] {
+			if !b.Start.Before(a.End) {
+				break
+			}
+
+			// the same meeting shows up on every calendar it was invited to
+			if a.Id == b.Id {
+				continue
+			}
+
+			conflicts = append(conflicts, CalendarConflict{A: a, B: b})
+		}
+	}
+
+	return conflicts
+}
+
+// isCalendarConflictCandidate reports whether ev takes up the user's time.
+// events without attendees have no response. they only count if the user made
+// them, i.e. they are on the primary calendar or organized by the user, and
+// not e.g. on a shared team or holiday calendar
+func isCalendarConflictCandidate(ev *CalendarEvent, primary bool) bool {
+	if ev.AllDay || ev.Transparent {
+		return false
+	}
+
+	switch ev.ResponseStatus {
+	case CalendarResponse_Accepted, CalendarResponse_Tentative:
+		return true
+	case "":
+		return primary || ev.IsOrganizer
+	}
+
+	return false
+}
@@ -0,0 +1,95 @@
+package gworkspace
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarMonitorConflicts(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	c, err := NewCalendarMonitor(nil, CalendarMonitorCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	start := now.Add(time.Hour)
+	// events on the primary calendar without attendees have no response, the
+	// ones on the team calendar were accepted
+	meeting := func(calendarId, id string, offset time.Duration) *CalendarEvent {
+		ev := &CalendarEvent{Id: id, CalendarId: calendarId, Start: start.Add(offset), End: start.Add(offset + time.Hour)}
+		if calendarId != "primary" {
+			ev.ResponseStatus = CalendarResponse_Accepted
+		}
+
+		return ev
+	}
+
+	primary := &calendarSyncState{Events: map[string]*CalendarEvent{
+		"standup": meeting("primary", "standup", 0),
+		"review":  meeting("primary", "review", time.Hour*3),
+	}}
+
+	team := &calendarSyncState{Events: map[string]*CalendarEvent{
+		// the same meeting on both calendars
+		"standup": meeting("team", "standup", 0),
+	}}
+
+	c.state = map[string]*calendarSyncState{"primary": primary, "team": team}
+
+	// existing conflicts are only remembered on the first check
+	team.Events["planning"] = meeting("team", "planning", time.Minute*30)
+
+	err = c.checkConflicts(t.Context(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case conflicts := <-c.Conflicts():
+		t.Fatalf("expected the first check to not report conflicts, got %+v", conflicts)
+	default:
+	}
+
+	free := meeting("team", "office-hours", time.Hour*3)
+	free.Transparent = true
+	declined := meeting("team", "offsite", time.Hour*3)
+	declined.ResponseStatus = CalendarResponse_Declined
+	// someone else's event on the team calendar that the user is not invited to
+	release := meeting("team", "release", time.Hour*3)
+	release.ResponseStatus = ""
+	team.Events["office-hours"] = free
+	team.Events["offsite"] = declined
+	team.Events["release"] = release
+	team.Events["retro"] = meeting("team", "retro", time.Hour*3+time.Minute*30)
+
+	err = c.checkConflicts(t.Context(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts := <-c.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].A.Id != "review" || conflicts[0].B.Id != "retro" {
+		t.Fatalf("expected only review to conflict with retro, got %+v", conflicts)
+	}
+
+	// a restarted monitor remembers the conflicts it reported
+	c, err = NewCalendarMonitor(nil, CalendarMonitorCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.state = map[string]*calendarSyncState{"primary": primary, "team": team}
+
+	err = c.checkConflicts(t.Context(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case conflicts := <-c.Conflicts():
+		t.Errorf("expected conflicts to only be reported once, got %+v", conflicts)
+	default:
+	}
+}
@@ -0,0 +1,125 @@
+package gworkspace
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type CalendarEventField string
+
+const (
+	CalendarEventField_Summary        CalendarEventField = "summary"
+	CalendarEventField_Time           CalendarEventField = "time"
+	CalendarEventField_Location       CalendarEventField = "location"
+	CalendarEventField_Attendees      CalendarEventField = "attendees"
+	CalendarEventField_ConferenceLink CalendarEventField = "conference_link"
+	CalendarEventField_Status         CalendarEventField = "status"
+)
+
+// CalendarFieldChange is a change to one field of an event
+type CalendarFieldChange struct {
+	Field CalendarEventField
+
+	// Description describes the change in a way that reads after the name of
+	// the event, e.g. "moved from 9:00 to 9:30"
+	Description string
+}
+
+// DiffCalendarEvents returns the changes between two versions of an event that
+// are worth telling the user about. the user's own response and reminders are
+// left out
+func DiffCalendarEvents(old, new *CalendarEvent) []CalendarFieldChange {
+	changes := make([]CalendarFieldChange, 0)
+
+	add := func(field CalendarEventField, format string, args ...any) {
+		changes = append(changes, CalendarFieldChange{Field: field, Description: fmt.Sprintf(format, args...)})
+	}
+
+	if old.Summary != new.Summary {
+		add(CalendarEventField_Summary, "renamed from %q", old.Summary)
+	}
+
+	if !old.Start.Equal(new.Start) {
+		add(CalendarEventField_Time, "moved from %s to %s", formatCalendarTime(old.Start, old.AllDay, new.Start), formatCalendarTime(new.Start, new.AllDay, old.Start))
+	} else if !old.End.Equal(new.End) {
+		add(CalendarEventField_Time, "now ends at %s", formatCalendarTime(new.End, new.AllDay, old.End))
+	}
+
+	switch {
+	case old.Location == new.Location:
+	case new.Location == "":
+		add(CalendarEventField_Location, "no longer has a location")
+	default:
+		add(CalendarEventField_Location, "moved to %s", new.Location)
+	}
+
+	added, removed := diffStrings(old.Attendees, new.Attendees)
+	if len(added) > 0 {
+		add(CalendarEventField_Attendees, "added %s", joinNames(added))
+	}
+
+	if len(removed) > 0 {
+		add(CalendarEventField_Attendees, "removed %s", joinNames(removed))
+	}
+
+	switch {
+	case old.ConferenceLink == new.ConferenceLink:
+	case new.ConferenceLink == "":
+		add(CalendarEventField_ConferenceLink, "no longer has a video call")
+	case old.ConferenceLink == "":
+		add(CalendarEventField_ConferenceLink, "now has a video call")
+	default:
+		add(CalendarEventField_ConferenceLink, "has a new video call link")
+	}
+
+	if old.Status != new.Status && new.Status != "" {
+		add(CalendarEventField_Status, "is now %s", new.Status)
+	}
+
+	return changes
+}
+
+// formatCalendarTime formats t as briefly as possible while still telling it
+// apart from other, the time it is shown next to
+func formatCalendarTime(t time.Time, allDay bool, other time.Time) string {
+	t = t.Local()
+	other = other.Local()
+
+	sameDay := t.YearDay() == other.YearDay() && t.Year() == other.Year()
+
+	switch {
+	case allDay:
+		return t.Format("Mon Jan 2")
+	case sameDay:
+		return t.Format("15:04")
+	default:
+		return t.Format("Mon Jan 2 15:04")
+	}
+}
+
+// diffStrings returns the strings that are only in b and only in a
+func diffStrings(a, b []string) (added []string, removed []string) {
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			added = append(added, s)
+		}
+	}
+
+	for _, s := range a {
+		if !slices.Contains(b, s) {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
+
+func joinNames(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
@@ -0,0 +1,93 @@
+package gworkspace_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+)
+
+func TestDiffCalendarEvents(t *testing.T) {
+	start := time.Date(2025, time.March, 3, 9, 0, 0, 0, time.Local)
+
+	old := &gworkspace.CalendarEvent{
+		Summary:        "Standup",
+		Start:          start,
+		End:            start.Add(time.Minute * 15),
+		Location:       "Room 1",
+		Attendees:      []string{"Alice", "Bob"},
+		ConferenceLink: "https://meet.google.com/abc",
+		Status:         "confirmed",
+		ResponseStatus: gworkspace.CalendarResponse_NeedsAction,
+	}
+
+	tests := []struct {
+		name     string
+		update   func(ev *gworkspace.CalendarEvent)
+		expected []string
+	}{
+		{
+			name: "moved",
+			update: func(ev *gworkspace.CalendarEvent) {
+				ev.Start = start.Add(time.Minute * 30)
+				ev.End = ev.Start.Add(time.Minute * 15)
+			},
+			expected: []string{"moved from 09:00 to 09:30"},
+		},
+		{
+			name: "moved to another day",
+			update: func(ev *gworkspace.CalendarEvent) {
+				ev.Start = start.AddDate(0, 0, 1)
+				ev.End = ev.Start.Add(time.Minute * 15)
+			},
+			expected: []string{"moved from Mon Mar 3 09:00 to Tue Mar 4 09:00"},
+		},
+		{
+			name:     "extended",
+			update:   func(ev *gworkspace.CalendarEvent) { ev.End = start.Add(time.Minute * 30) },
+			expected: []string{"now ends at 09:30"},
+		},
+		{
+			name: "attendees and location",
+			update: func(ev *gworkspace.CalendarEvent) {
+				ev.Attendees = []string{"Bob", "Carol", "Dave"}
+				ev.Location = ""
+			},
+			expected: []string{"no longer has a location", "added Carol and Dave", "removed Alice"},
+		},
+		{
+			name: "conference link and status",
+			update: func(ev *gworkspace.CalendarEvent) {
+				ev.ConferenceLink = "https://meet.google.com/def"
+				ev.Status = "tentative"
+			},
+			expected: []string{"has a new video call link", "is now tentative"},
+		},
+		{
+			name: "own response and reminders",
+			update: func(ev *gworkspace.CalendarEvent) {
+				ev.ResponseStatus = gworkspace.CalendarResponse_Accepted
+				ev.ReminderMinutes = []int64{5}
+			},
+			expected: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			new := *old
+			test.update(&new)
+
+			diff := gworkspace.DiffCalendarEvents(old, &new)
+			if len(diff) != len(test.expected) {
+				t.Fatalf("expected %d changes, got %+v", len(test.expected), diff)
+			}
+
+			for i, expected := range test.expected {
+				if diff[i].Description != expected {
+					t.Errorf("expected %q, got %q", expected, diff[i].Description)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package gworkspace
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	CalendarEventType_Default     = "default"
+	CalendarEventType_FocusTime   = "focusTime"
+	CalendarEventType_OutOfOffice = "outOfOffice"
+)
+
+// CalendarDndRule matches events that turn on do not disturb. every condition
+// that is set has to match, and a rule without conditions matches nothing
+type CalendarDndRule struct {
+	// Keyword matches events with the keyword in their summary, ignoring case
+	Keyword string
+
+	// MinAttendees matches events with at least this many other attendees
+	MinAttendees int
+
+	// BusyOnly limits the rule to events that show the user as busy
+	BusyOnly bool
+}
+
+type CalendarDndCfg struct {
+	FocusTime   bool
+	OutOfOffice bool
+	Rules       []CalendarDndRule
+}
+
+// CalendarDnd is the event that do not disturb is on for
+type CalendarDnd struct {
+	Event *CalendarEvent
+
+	// Reason is shown to the user, e.g. "Focus time: Deep work"
+	Reason string
+}
+
+// DoNotDisturb returns the event in progress that do not disturb should be on
+// for, or nil. if several events match, the one that ends last is returned
+func (c *CalendarMonitor) DoNotDisturb(now time.Time) *CalendarDnd {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var dnd *CalendarDnd
+	for _, s := range c.state {
+		for _, ev := range s.Events {
+			if now.Before(ev.Start) || !now.Before(ev.End) || ev.ResponseStatus == CalendarResponse_Declined {
+				continue
+			}
+
+			reason, ok := c.cfg.Dnd.reason(ev)
+			if !ok {
+				continue
+			}
+
+			if dnd == nil || ev.End.After(dnd.Event.End) {
+				dnd = &CalendarDnd{Event: ev, Reason: reason}
+			}
+		}
+	}
+
+	return dnd
+}
+
+// reason returns why ev turns on do not disturb, if it does
+func (cfg *CalendarDndCfg) reason(ev *CalendarEvent) (string, bool) {
+	summary := ev.Summary
+	if summary == "" {
+		summary = "(No title)"
+	}
+
+	switch {
+	case ev.EventType == CalendarEventType_OutOfOffice && cfg.OutOfOffice:
+		return "Out of office", true
+	case ev.EventType == CalendarEventType_FocusTime && cfg.FocusTime:
+		return "Focus time: " + summary, true
+	case ev.AllDay:
+		return "", false
+	}
+
+	for _, r := range cfg.Rules {
+		if r.matches(ev) {
+			return "In " + summary, true
+		}
+	}
+
+	return "", false
+}
+
+func (r *CalendarDndRule) matches(ev *CalendarEvent) bool {
+	if r.Keyword == "" && r.MinAttendees == 0 && !r.BusyOnly {
+		return false
+	}
+
+	if r.Keyword != "" && !strings.Contains(strings.ToLower(ev.Summary), strings.ToLower(r.Keyword)) {
+		return false
+	}
+
+	if len(ev.Attendees) < r.MinAttendees {
+		return false
+	}
+
+	if r.BusyOnly && ev.Transparent {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,102 @@
+package gworkspace
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarMonitorDoNotDisturb(t *testing.T) {
+	now := time.Now()
+
+	event := func(id string, end time.Duration, f func(ev *CalendarEvent)) *CalendarEvent {
+		ev := &CalendarEvent{Id: id, Summary: id, Start: now.Add(-time.Minute), End: now.Add(end)}
+		f(ev)
+		return ev
+	}
+
+	tests := []struct {
+		name     string
+		events   []*CalendarEvent
+		expected string
+	}{
+		{
+			name: "focus time",
+			events: []*CalendarEvent{
+				event("Deep work", time.Hour, func(ev *CalendarEvent) { ev.EventType = CalendarEventType_FocusTime }),
+			},
+			expected: "Focus time: Deep work",
+		},
+		{
+			name: "big busy meeting",
+			events: []*CalendarEvent{
+				event("All hands", time.Hour, func(ev *CalendarEvent) { ev.Attendees = []string{"a", "b", "c"} }),
+			},
+			expected: "In All hands",
+		},
+		{
+			name: "small or free meetings",
+			events: []*CalendarEvent{
+				event("1:1", time.Hour, func(ev *CalendarEvent) { ev.Attendees = []string{"a"} }),
+				event("Webinar", time.Hour, func(ev *CalendarEvent) {
+					ev.Attendees = []string{"a", "b", "c"}
+					ev.Transparent = true
+				}),
+			},
+		},
+		{
+			name: "keyword",
+			events: []*CalendarEvent{
+				event("Customer presentation", time.Hour, func(ev *CalendarEvent) {}),
+			},
+			expected: "In Customer presentation",
+		},
+		{
+			name: "ends last wins",
+			events: []*CalendarEvent{
+				event("Deep work", time.Minute*10, func(ev *CalendarEvent) { ev.EventType = CalendarEventType_FocusTime }),
+				event("Vacation", time.Hour*48, func(ev *CalendarEvent) { ev.EventType = CalendarEventType_OutOfOffice }),
+			},
+			expected: "Out of office",
+		},
+		{
+			name: "declined and over",
+			events: []*CalendarEvent{
+				event("Deep work", time.Hour, func(ev *CalendarEvent) {
+					ev.EventType = CalendarEventType_FocusTime
+					ev.ResponseStatus = CalendarResponse_Declined
+				}),
+				event("Presentation", -time.Second, func(ev *CalendarEvent) {}),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := make(map[string]*CalendarEvent, len(test.events))
+			for _, ev := range test.events {
+				events[ev.Id] = ev
+			}
+
+			c := &CalendarMonitor{
+				cfg: CalendarMonitorCfg{Dnd: CalendarDndCfg{
+					FocusTime:   true,
+					OutOfOffice: true,
+					Rules: []CalendarDndRule{
+						{MinAttendees: 3, BusyOnly: true},
+						{Keyword: "presentation"},
+					},
+				}},
+				state: map[string]*calendarSyncState{"primary": {Events: events}},
+			}
+
+			var reason string
+			if dnd := c.DoNotDisturb(now); dnd != nil {
+				reason = dnd.Reason
+			}
+
+			if reason != test.expected {
+				t.Errorf("expected reason %q, got %q", test.expected, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package gworkspace
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// calendarMeetingLinkPatterns match the join links of video call providers
+// that are not integrated with google calendar. the links usually end up in
+// the location or description of the event
+var calendarMeetingLinkPatterns = []*regexp.Regexp{
+	// zoom and zoom for government, personal and webinar links
+	regexp.MustCompile(`https://(?:[\w-]+\.)*zoom(?:gov)?\.(?:us|com)/(?:j|my|w|s|wc/join)/[^\s"'<>]+`),
+
+	// teams for work and personal
+	regexp.MustCompile(`https://teams\.microsoft\.com/l/meetup-join/[^\s"'<>]+`),
+	regexp.MustCompile(`https://teams\.live\.com/meet/[^\s"'<>]+`),
+
+	// webex meeting and personal room links
+	regexp.MustCompile(`https://(?:[\w-]+\.)*webex\.com/(?:meet/|join/|[\w-]+/j\.php\?|[\w-]+/join)[^\s"'<>]*`),
+}
+
+// calendarConferenceLink finds the link used to join the call of an event. the
+// conference data and hangout link are set for meet, and for providers with a
+// calendar add-on. anything else is found by looking for known links in the
+// location and description. only web links are returned, since the link is
+// opened as is and add-ons can set any uri
+func calendarConferenceLink(item *calendar.Event) string {
+	if item.ConferenceData != nil {
+		for _, e := range item.ConferenceData.EntryPoints {
+			if e.EntryPointType == "video" && isWebLink(e.Uri) {
+				return e.Uri
+			}
+		}
+	}
+
+	if isWebLink(item.HangoutLink) {
+		return item.HangoutLink
+	}
+
+	// descriptions are html, so links may have escaped query strings
+	for _, text := range []string{item.Location, html.UnescapeString(item.Description)} {
+		for _, p := range calendarMeetingLinkPatterns {
+			if link := p.FindString(text); link != "" {
+				// punctuation after a link in plain text
+				return strings.TrimRight(link, ".,;:)")
+			}
+		}
+	}
+
+	return ""
+}
+
+func isWebLink(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
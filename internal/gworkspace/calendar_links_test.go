@@ -0,0 +1,72 @@
+package gworkspace
+
+import (
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestCalendarConferenceLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     *calendar.Event
+		expected string
+	}{
+		{
+			name: "conference data",
+			item: &calendar.Event{
+				HangoutLink: "https://meet.google.com/old",
+				ConferenceData: &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{
+					{EntryPointType: "phone", Uri: "tel:+1-555-0100"},
+					{EntryPointType: "video", Uri: "https://meet.google.com/abc-defg-hij"},
+				}},
+			},
+			expected: "https://meet.google.com/abc-defg-hij",
+		},
+		{
+			name: "video entry point that is not a web link",
+			item: &calendar.Event{ConferenceData: &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{
+				{EntryPointType: "video", Uri: "file:///tmp/join.sh"},
+			}}},
+			expected: "",
+		},
+		{
+			name:     "hangout link",
+			item:     &calendar.Event{HangoutLink: "https://meet.google.com/abc-defg-hij"},
+			expected: "https://meet.google.com/abc-defg-hij",
+		},
+		{
+			name:     "zoom in location",
+			item:     &calendar.Event{Location: "https://example.zoom.us/j/123456789?pwd=abc, Room 1"},
+			expected: "https://example.zoom.us/j/123456789?pwd=abc",
+		},
+		{
+			name:     "teams in html description",
+			item:     &calendar.Event{Description: `Join: <a href="https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0?context=%7b%7d&amp;x=1">Click here</a>`},
+			expected: "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0?context=%7b%7d&x=1",
+		},
+		{
+			name:     "webex in description",
+			item:     &calendar.Event{Description: "Meeting link: https://acme.webex.com/acme/j.php?MTID=m123."},
+			expected: "https://acme.webex.com/acme/j.php?MTID=m123",
+		},
+		{
+			name:     "location preferred over description",
+			item:     &calendar.Event{Location: "https://zoom.us/my/alice", Description: "https://teams.live.com/meet/123"},
+			expected: "https://zoom.us/my/alice",
+		},
+		{
+			name:     "no link",
+			item:     &calendar.Event{Location: "Room 1", Description: "See https://example.com/agenda"},
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if link := calendarConferenceLink(test.item); link != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, link)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+var ErrNotInvited = errors.New("user is not invited to the event")
+
+// CalendarRsvp is a response to an invitation. the values are the response
+// statuses used by the api
+type CalendarRsvp string
+
+const (
+	CalendarRsvp_Yes   CalendarRsvp = CalendarResponse_Accepted
+	CalendarRsvp_No    CalendarRsvp = CalendarResponse_Declined
+	CalendarRsvp_Maybe CalendarRsvp = CalendarResponse_Tentative
+)
+
+var AllCalendarRsvps = []CalendarRsvp{
+	CalendarRsvp_Yes,
+	CalendarRsvp_No,
+	CalendarRsvp_Maybe,
+}
+
+func ParseCalendarRsvp(s string) (CalendarRsvp, error) {
+	r := CalendarRsvp(s)
+	if !slices.Contains(AllCalendarRsvps, r) {
+		return "", fmt.Errorf("unknown calendar response %q", s)
+	}
+
+	return r, nil
+}
+
+// Title is the text shown to the user for the response
+func (r CalendarRsvp) Title() string {
+	switch r {
+	case CalendarRsvp_Yes:
+		return "Yes"
+	case CalendarRsvp_No:
+		return "No"
+	case CalendarRsvp_Maybe:
+		return "Maybe"
+	}
+
+	return string(r)
+}
+
+// Respond sets the user's response to the invitation to an event, along with
+// an optional comment for the organizer. the cached event is updated straight
+// away so that reminders of declined events stop without waiting for a sync
+func (c *CalendarMonitor) Respond(ctx context.Context, calendarId, eventId string, rsvp CalendarRsvp, comment string) error {
+	item, err := c.svc.Events.Get(calendarId, eventId).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error while fetching event: %w", err)
+	}
+
+	// the attendees are replaced as a whole, so every other attendee has to
+	// be sent back unchanged
+	found := false
+	for _, a := range item.Attendees {
+		if a.Self {
+			a.ResponseStatus = string(rsvp)
+			if comment != "" {
+				a.Comment = comment
+			}
+
+			found = true
+		}
+	}
+
+	if !found {
+		return ErrNotInvited
+	}
+
+	_, err = c.svc.Events.Patch(calendarId, eventId, &calendar.Event{Attendees: item.Attendees}).
+		SendUpdates("all").
+		Context(ctx).
+		Do()
+
+	if err != nil {
+		return fmt.Errorf("error while updating response: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.state[calendarId]
+	if prev == nil || prev.Events[eventId] == nil {
+		return nil
+	}
+
+	state := *prev
+	state.Events = maps.Clone(prev.Events)
+
+	ev := *prev.Events[eventId]
+	ev.ResponseStatus = string(rsvp)
+	state.Events[eventId] = &ev
+
+	c.reschedule(map[string]*calendarSyncState{calendarId: prev}, map[string]*calendarSyncState{calendarId: &state})
+	c.state[calendarId] = &state
+
+	// the response was sent, so a failure to save is only logged. the next
+	// sync saves the state again
+	err = c.save()
+	if err != nil {
+		slog.Error("error while saving calendar sync state", "error", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package gworkspace
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestCalendarMonitorRespond(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	invite := testCalendarEvent("interview", "Interview", start)
+	invite.Attendees = []*calendar.EventAttendee{
+		{Email: "recruiter@example.com", ResponseStatus: CalendarResponse_Accepted},
+		{Email: "me@example.com", Self: true, ResponseStatus: CalendarResponse_NeedsAction},
+	}
+
+	f := &fakeCalendar{events: []*calendar.Event{invite, testCalendarEvent("lunch", "Lunch", start)}}
+
+	c, err := NewCalendarMonitor(newTestCalendarService(t, f), CalendarMonitorCfg{Calendars: []CalendarSelection{{Id: "primary", Remind: true, NotifyChanges: true}}, LookAhead: time.Hour * 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Refresh(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Respond(t.Context(), "primary", "interview", CalendarRsvp_No, "clashes with another meeting")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every attendee is sent back, with only the user's response changed
+	if f.patched == nil || len(f.patched.Attendees) != 2 {
+		t.Fatalf("expected attendees to be patched, got %+v", f.patched)
+	}
+
+	other, self := f.patched.Attendees[0], f.patched.Attendees[1]
+	if other.ResponseStatus != CalendarResponse_Accepted {
+		t.Errorf("expected other attendee to be unchanged, got %s", other.ResponseStatus)
+	}
+
+	if self.ResponseStatus != CalendarResponse_Declined || self.Comment != "clashes with another meeting" {
+		t.Errorf("expected declined with comment, got %s %q", self.ResponseStatus, self.Comment)
+	}
+
+	if got := c.state["primary"].Events["interview"].ResponseStatus; got != CalendarResponse_Declined {
+		t.Errorf("expected cached response to be updated, got %s", got)
+	}
+
+	for _, r := range c.scheduled {
+		if r.Event.Id == "interview" {
+			t.Errorf("expected reminders of the declined event to be dropped")
+		}
+	}
+
+	err = c.Respond(t.Context(), "primary", "lunch", CalendarRsvp_Yes, "")
+	if !errors.Is(err, ErrNotInvited) {
+		t.Errorf("expected %v for an event without attendees, got %v", ErrNotInvited, err)
+	}
+}
@@ -0,0 +1,296 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const calendarSyncFilePath = "calendar_sync.json"
+
+type CalendarChangeKind string
+
+const (
+	// CalendarChangeKind_Added is an event the user added themselves, or that
+	// they already responded to
+	CalendarChangeKind_Added CalendarChangeKind = "added"
+
+	// CalendarChangeKind_Invited is a new event the user was invited to and
+	// has not responded to yet
+	CalendarChangeKind_Invited   CalendarChangeKind = "invited"
+	CalendarChangeKind_Updated   CalendarChangeKind = "updated"
+	CalendarChangeKind_Cancelled CalendarChangeKind = "cancelled"
+)
+
+// CalendarEventChange is a change to an upcoming event found while syncing
+type CalendarEventChange struct {
+	Kind CalendarChangeKind
+
+	// Event is the event after the change, or before it was cancelled
+	Event *CalendarEvent
+
+	// Previous is the event before it was updated, or nil
+	Previous *CalendarEvent
+}
+
+// calendarSyncState is what is kept between syncs of a calendar. the sync
+// token only covers the time window of the full sync it came from, so a new
+// full sync is done once the window runs short
+type calendarSyncState struct {
+	// Name is the name the user gave the calendar
+	Name string
+
+	// Primary is set for the user's own calendar
+	Primary bool
+
+	SyncToken string
+
+	WindowStart time.Time
+	WindowEnd   time.Time
+
+	// SyncedAt is when the calendar was last synced. events that were not
+	// updated since are not reported when they first show up
+	SyncedAt time.Time
+
+	// DefaultReminders are the popup reminders of the calendar, in minutes
+	DefaultReminders []int64
+
+	Events map[string]*CalendarEvent
+}
+
+// sync brings the cached events of the calendar up to date and returns what
+// changed. the returned state replaces the previous one
+func (c *CalendarMonitor) sync(ctx context.Context, calendarId string, prev *calendarSyncState, now time.Time) (*calendarSyncState, []*CalendarEventChange, error) {
+	if prev == nil || prev.SyncToken == "" || now.Add(c.cfg.LookAhead/2).After(prev.WindowEnd) {
+		return c.fullSync(ctx, calendarId, prev, now)
+	}
+
+	state, changes, err := c.incrementalSync(ctx, calendarId, prev, now)
+
+	// 410 when the sync token has expired
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusGone {
+		slog.Info("calendar sync token expired, doing a full sync", "calendarId", calendarId)
+		return c.fullSync(ctx, calendarId, prev, now)
+	}
+
+	return state, changes, err
+}
+
+// fullSync lists every event in a new window and diffs it against the cached
+// events of prev
+func (c *CalendarMonitor) fullSync(ctx context.Context, calendarId string, prev *calendarSyncState, now time.Time) (*calendarSyncState, []*CalendarEventChange, error) {
+	slog.Debug("doing full calendar sync", "calendarId", calendarId)
+
+	cal, err := c.svc.CalendarList.Get(calendarId).Context(ctx).Do()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while fetching calendar: %w", err)
+	}
+
+	name := cal.SummaryOverride
+	if name == "" {
+		name = cal.Summary
+	}
+
+	state := &calendarSyncState{
+		Name:             name,
+		Primary:          cal.Primary,
+		WindowStart:      now,
+		WindowEnd:        now.Add(c.cfg.LookAhead),
+		SyncedAt:         now,
+		DefaultReminders: popupReminderMinutes(cal.DefaultReminders),
+		Events:           make(map[string]*CalendarEvent),
+	}
+
+	err = c.svc.Events.List(calendarId).
+		TimeMin(state.WindowStart.Format(time.RFC3339)).
+		TimeMax(state.WindowEnd.Format(time.RFC3339)).
+		SingleEvents(true).
+		Pages(ctx, func(res *calendar.Events) error {
+			for _, item := range res.Items {
+				if item.Status == "cancelled" {
+					continue
+				}
+
+				ev, err := newCalendarEvent(calendarId, item, state.DefaultReminders)
+				if err != nil {
+					slog.Warn("skipping calendar event", "eventId", item.Id, "error", err)
+					continue
+				}
+
+				state.Events[ev.Id] = ev
+			}
+
+			if res.NextSyncToken != "" {
+				state.SyncToken = res.NextSyncToken
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while listing events: %w", err)
+	}
+
+	if prev == nil {
+		return state, nil, nil
+	}
+
+	changes := make([]*CalendarEventChange, 0)
+	for id, ev := range state.Events {
+		old, ok := prev.Events[id]
+		if !ok {
+			// the new window reaches further than the previous one. events
+			// that are only in the part it gained were not added, they just
+			// were not listed before
+			if ev.Start.Before(prev.WindowEnd) {
+				changes = append(changes, newCalendarAddedChange(ev))
+			}
+		} else if !old.Equal(ev) {
+			changes = append(changes, &CalendarEventChange{Kind: CalendarChangeKind_Updated, Event: ev, Previous: old})
+		}
+	}
+
+	for id, old := range prev.Events {
+		if _, ok := state.Events[id]; ok {
+			continue
+		}
+
+		// events that ended or were never in the new window are simply
+		// forgotten. anything else would have been listed if it still existed
+		if old.End.After(now) && old.Start.Before(state.WindowEnd) {
+			changes = append(changes, &CalendarEventChange{Kind: CalendarChangeKind_Cancelled, Event: old})
+		}
+	}
+
+	return state, changes, nil
+}
+
+// incrementalSync fetches the events changed since the last sync
+func (c *CalendarMonitor) incrementalSync(ctx context.Context, calendarId string, prev *calendarSyncState, now time.Time) (*calendarSyncState, []*CalendarEventChange, error) {
+	state := &calendarSyncState{
+		Name:             prev.Name,
+		Primary:          prev.Primary,
+		SyncToken:        prev.SyncToken,
+		WindowStart:      prev.WindowStart,
+		WindowEnd:        prev.WindowEnd,
+		SyncedAt:         now,
+		DefaultReminders: prev.DefaultReminders,
+		Events:           make(map[string]*CalendarEvent, len(prev.Events)),
+	}
+
+	for id, ev := range prev.Events {
+		// forget events that are over
+		if ev.End.After(now) {
+			state.Events[id] = ev
+		}
+	}
+
+	changes := make([]*CalendarEventChange, 0)
+
+	err := c.svc.Events.List(calendarId).
+		SyncToken(prev.SyncToken).
+		SingleEvents(true).
+		Pages(ctx, func(res *calendar.Events) error {
+			for _, item := range res.Items {
+				old, cached := state.Events[item.Id]
+
+				if item.Status == "cancelled" {
+					if cached {
+						delete(state.Events, item.Id)
+						changes = append(changes, &CalendarEventChange{Kind: CalendarChangeKind_Cancelled, Event: old})
+					}
+
+					continue
+				}
+
+				ev, err := newCalendarEvent(calendarId, item, state.DefaultReminders)
+				if err != nil {
+					slog.Warn("skipping calendar event", "eventId", item.Id, "error", err)
+					continue
+				}
+
+				inWindow := ev.End.After(now) && ev.Start.Before(state.WindowEnd)
+
+				switch {
+				case inWindow && !cached && !calendarEventUpdatedSince(item, prev.SyncedAt):
+					// not changed since the last sync, so it is not new
+					state.Events[ev.Id] = ev
+				case inWindow && !cached:
+					state.Events[ev.Id] = ev
+					changes = append(changes, newCalendarAddedChange(ev))
+				case inWindow && !old.Equal(ev):
+					state.Events[ev.Id] = ev
+					changes = append(changes, &CalendarEventChange{Kind: CalendarChangeKind_Updated, Event: ev, Previous: old})
+				case !inWindow && cached:
+					// moved out of the window
+					delete(state.Events, ev.Id)
+					changes = append(changes, &CalendarEventChange{Kind: CalendarChangeKind_Updated, Event: ev, Previous: old})
+				}
+			}
+
+			if res.NextSyncToken != "" {
+				state.SyncToken = res.NextSyncToken
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while listing changed events: %w", err)
+	}
+
+	return state, changes, nil
+}
+
+// calendarEventUpdatedSince reports whether item was modified after t. an
+// unknown time counts as modified
+func calendarEventUpdatedSince(item *calendar.Event, t time.Time) bool {
+	if t.IsZero() {
+		return true
+	}
+
+	updated, err := time.Parse(time.RFC3339, item.Updated)
+	if err != nil {
+		return true
+	}
+
+	return updated.After(t)
+}
+
+func newCalendarAddedChange(ev *CalendarEvent) *CalendarEventChange {
+	kind := CalendarChangeKind_Added
+	if !ev.IsOrganizer && ev.ResponseStatus == CalendarResponse_NeedsAction {
+		kind = CalendarChangeKind_Invited
+	}
+
+	return &CalendarEventChange{Kind: kind, Event: ev}
+}
+
+// Equal reports whether two versions of an event differ in anything that is
+// shown to the user or affects reminders
+func (e *CalendarEvent) Equal(o *CalendarEvent) bool {
+	return e.Id == o.Id &&
+		e.CalendarId == o.CalendarId &&
+		e.Summary == o.Summary &&
+		e.Location == o.Location &&
+		e.Status == o.Status &&
+		e.EventType == o.EventType &&
+		e.Transparent == o.Transparent &&
+		e.ConferenceLink == o.ConferenceLink &&
+		e.Organizer == o.Organizer &&
+		e.IsOrganizer == o.IsOrganizer &&
+		e.Start.Equal(o.Start) &&
+		e.End.Equal(o.End) &&
+		e.AllDay == o.AllDay &&
+		e.ResponseStatus == o.ResponseStatus &&
+		slices.Equal(e.Attendees, o.Attendees) &&
+		slices.Equal(e.ReminderMinutes, o.ReminderMinutes)
+}
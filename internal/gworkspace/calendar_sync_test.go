@@ -0,0 +1,276 @@
+package gworkspace
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// unlike the other monitors, the calendar tests are in the package. they check
+// the reminders a sync schedules and what is kept per calendar, neither of
+// which can be seen from outside until the reminders fall due by the wall
+// clock
+
+// fakeCalendar serves the calendar list entry and events of the primary
+// calendar. requests with a sync token get changes, or 410 if it has expired.
+// patches to an event are recorded in patched
+type fakeCalendar struct {
+	events       []*calendar.Event
+	changes      []*calendar.Event
+	tokenExpired bool
+
+	patched *calendar.Event
+}
+
+func newTestCalendarService(t *testing.T, f *fakeCalendar) *calendar.Service {
+	opts := FakeApi(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/users/me/calendarList"):
+			json.NewEncoder(w).Encode(&calendar.CalendarList{Items: []*calendar.CalendarListEntry{
+				{Id: "me@example.com", Summary: "me@example.com", Primary: true, AccessRole: "owner"},
+				{Id: "oncall@group.calendar.google.com", Summary: "On-call", SummaryOverride: "Team on-call", AccessRole: "reader"},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/calendarList/primary"):
+			json.NewEncoder(w).Encode(&calendar.CalendarListEntry{
+				Id:               "primary",
+				DefaultReminders: []*calendar.EventReminder{{Method: "popup", Minutes: 10}},
+			})
+		case strings.HasSuffix(r.URL.Path, "/calendars/primary/events"):
+			if r.URL.Query().Get("syncToken") == "" {
+				json.NewEncoder(w).Encode(&calendar.Events{Items: f.events, NextSyncToken: "full"})
+				return
+			}
+
+			if f.tokenExpired {
+				w.WriteHeader(http.StatusGone)
+				json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": 410, "message": "gone"}})
+				return
+			}
+
+			json.NewEncoder(w).Encode(&calendar.Events{Items: f.changes, NextSyncToken: "incremental"})
+		case strings.HasPrefix(r.URL.Path, "/calendars/primary/events/"):
+			id := strings.TrimPrefix(r.URL.Path, "/calendars/primary/events/")
+			idx := slices.IndexFunc(f.events, func(e *calendar.Event) bool { return e.Id == id })
+			if idx == -1 {
+				http.NotFound(w, r)
+				return
+			}
+
+			if r.Method == http.MethodPatch {
+				f.patched = &calendar.Event{}
+				json.NewDecoder(r.Body).Decode(f.patched)
+			}
+
+			json.NewEncoder(w).Encode(f.events[idx])
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	svc, err := calendar.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return svc
+}
+
+func testCalendarEvent(id, summary string, start time.Time) *calendar.Event {
+	return &calendar.Event{
+		Id:        id,
+		Summary:   summary,
+		Start:     &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:       &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+		Reminders: &calendar.EventReminders{UseDefault: true},
+	}
+}
+
+func changeKinds(changes []*CalendarEventChange) map[string]CalendarChangeKind {
+	kinds := make(map[string]CalendarChangeKind, len(changes))
+	for _, c := range changes {
+		kinds[c.Event.Id] = c.Kind
+	}
+
+	return kinds
+}
+
+func TestCalendarMonitorIncrementalSync(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	f := &fakeCalendar{
+		events: []*calendar.Event{
+			testCalendarEvent("standup", "Standup", start),
+			testCalendarEvent("review", "Review", start),
+		},
+	}
+
+	cfg := CalendarMonitorCfg{Calendars: []CalendarSelection{{Id: "primary", Remind: true, NotifyChanges: true}}, LookAhead: time.Hour * 24}
+	c, err := NewCalendarMonitor(newTestCalendarService(t, f), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Refresh(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case changes := <-c.Changes():
+		t.Fatalf("expected the first sync to not report changes, got %v", changeKinds(changes))
+	default:
+	}
+
+	if len(c.scheduled) != 2 {
+		t.Fatalf("expected a reminder for each event, got %d", len(c.scheduled))
+	}
+
+	// a restarted monitor continues from the persisted state
+	c, err = NewCalendarMonitor(newTestCalendarService(t, f), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled := testCalendarEvent("review", "", start)
+	cancelled.Status = "cancelled"
+	invite := testCalendarEvent("interview", "Interview", start)
+	invite.Organizer = &calendar.EventOrganizer{Email: "recruiter@example.com"}
+	invite.Attendees = []*calendar.EventAttendee{{Email: "me@example.com", Self: true, ResponseStatus: CalendarResponse_NeedsAction}}
+
+	f.changes = []*calendar.Event{
+		testCalendarEvent("standup", "Standup", start.Add(time.Minute*30)),
+		testCalendarEvent("lunch", "Lunch", start),
+		invite,
+		cancelled,
+	}
+
+	err = c.Refresh(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := changeKinds(<-c.Changes())
+	expected := map[string]CalendarChangeKind{
+		"standup":   CalendarChangeKind_Updated,
+		"lunch":     CalendarChangeKind_Added,
+		"interview": CalendarChangeKind_Invited,
+		"review":    CalendarChangeKind_Cancelled,
+	}
+
+	if len(kinds) != len(expected) {
+		t.Fatalf("expected changes %v, got %v", expected, kinds)
+	}
+
+	for id, kind := range expected {
+		if kinds[id] != kind {
+			t.Errorf("expected %s to be %s, got %s", id, kind, kinds[id])
+		}
+	}
+
+	for _, r := range c.scheduled {
+		if r.Event.Id == "standup" && !r.Event.Start.Equal(start.Add(time.Minute*30)) {
+			t.Errorf("expected reminder of moved event to be rescheduled")
+		}
+
+		if r.Event.Id == "review" {
+			t.Errorf("expected reminder of cancelled event to be removed")
+		}
+	}
+}
+
+func TestCalendarMonitorResyncsWhenTokenExpires(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	f := &fakeCalendar{
+		events: []*calendar.Event{
+			testCalendarEvent("standup", "Standup", start),
+			testCalendarEvent("review", "Review", start),
+		},
+	}
+
+	c, err := NewCalendarMonitor(newTestCalendarService(t, f), CalendarMonitorCfg{Calendars: []CalendarSelection{{Id: "primary", Remind: true, NotifyChanges: true}}, LookAhead: time.Hour * 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Refresh(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.tokenExpired = true
+	f.events = []*calendar.Event{
+		testCalendarEvent("standup", "Standup", start),
+		testCalendarEvent("lunch", "Lunch", start),
+	}
+
+	err = c.Refresh(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unchanged event is not reported
+	kinds := changeKinds(<-c.Changes())
+	if len(kinds) != 2 || kinds["lunch"] != CalendarChangeKind_Added || kinds["review"] != CalendarChangeKind_Cancelled {
+		t.Errorf("expected lunch to be added and review to be cancelled, got %v", kinds)
+	}
+}
+
+func TestCalendarSyncOnlyReportsEventsInThePreviousWindow(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	now := time.Now().Truncate(time.Second)
+	f := &fakeCalendar{
+		events: []*calendar.Event{
+			testCalendarEvent("standup", "Standup", now.Add(time.Hour)),
+		},
+	}
+
+	c, err := NewCalendarMonitor(newTestCalendarService(t, f), CalendarMonitorCfg{Calendars: []CalendarSelection{{Id: "primary", NotifyChanges: true}}, LookAhead: time.Hour * 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, _, err := c.sync(t.Context(), "primary", nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an event that was there all along but had not been updated since the
+	// last sync is kept without being reported
+	unchanged := testCalendarEvent("planning", "Planning", now.Add(time.Hour*2))
+	unchanged.Updated = now.Add(-time.Hour).Format(time.RFC3339)
+	f.changes = []*calendar.Event{unchanged}
+
+	state, changes, err := c.sync(t.Context(), "primary", state, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 0 || state.Events["planning"] == nil {
+		t.Fatalf("expected the unchanged event to be kept without a change, got %v", changeKinds(changes))
+	}
+
+	// half the look ahead later the window is moved forward
+	f.events = []*calendar.Event{
+		testCalendarEvent("retro", "Retro", now.Add(time.Hour*20)),
+		testCalendarEvent("offsite", "Offsite", now.Add(time.Hour*30)),
+	}
+
+	_, changes, err = c.sync(t.Context(), "primary", state, now.Add(time.Hour*13))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := changeKinds(changes)
+	if len(kinds) != 1 || kinds["retro"] != CalendarChangeKind_Added {
+		t.Errorf("expected only retro to be added, got %v", kinds)
+	}
+}
@@ -0,0 +1,209 @@
+package gworkspace
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestNewCalendarEventReminders(t *testing.T) {
+	defaults := []int64{10}
+
+	tests := []struct {
+		name      string
+		reminders *calendar.EventReminders
+		expected  []int64
+	}{
+		{
+			name:      "calendar defaults",
+			reminders: &calendar.EventReminders{UseDefault: true},
+			expected:  []int64{10},
+		},
+		{
+			name: "overrides",
+			reminders: &calendar.EventReminders{Overrides: []*calendar.EventReminder{
+				{Method: "popup", Minutes: 30},
+				{Method: "email", Minutes: 60},
+				{Method: "popup", Minutes: 5},
+			}},
+			expected: []int64{30, 5},
+		},
+		{
+			name:      "no reminders",
+			reminders: &calendar.EventReminders{},
+			expected:  []int64{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ev, err := newCalendarEvent("primary", &calendar.Event{
+				Id:        "event",
+				Start:     &calendar.EventDateTime{DateTime: "2025-03-01T10:00:00Z"},
+				End:       &calendar.EventDateTime{DateTime: "2025-03-01T11:00:00Z"},
+				Reminders: test.reminders,
+			}, defaults)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(ev.ReminderMinutes, test.expected) {
+				t.Errorf("expected reminders %v, got %v", test.expected, ev.ReminderMinutes)
+			}
+		})
+	}
+}
+
+func TestNewCalendarEventAttendees(t *testing.T) {
+	ev, err := newCalendarEvent("primary", &calendar.Event{
+		Start: &calendar.EventDateTime{Date: "2025-03-01"},
+		End:   &calendar.EventDateTime{Date: "2025-03-02"},
+		Attendees: []*calendar.EventAttendee{
+			{Email: "me@example.com", Self: true, ResponseStatus: CalendarResponse_Tentative},
+			{Email: "alice@example.com", DisplayName: "Alice"},
+			{Email: "bob@example.com"},
+			{Email: "room@resource.calendar.google.com", Resource: true},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ev.AllDay {
+		t.Errorf("expected all day event")
+	}
+
+	if expected := []string{"Alice", "bob@example.com"}; !slices.Equal(ev.Attendees, expected) {
+		t.Errorf("expected attendees %v, got %v", expected, ev.Attendees)
+	}
+
+	if ev.ResponseStatus != CalendarResponse_Tentative {
+		t.Errorf("expected response status %s, got %s", CalendarResponse_Tentative, ev.ResponseStatus)
+	}
+}
+
+func TestCalendarMonitorRemindsOnce(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	c, err := NewCalendarMonitor(nil, CalendarMonitorCfg{Calendars: []CalendarSelection{
+		{Id: "primary", Remind: true},
+		{Id: "team", NotifyChanges: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	events := []*CalendarEvent{
+		{Id: "soon", CalendarId: "primary", Start: now.Add(time.Minute * 5), ReminderMinutes: []int64{10, 1}},
+		{Id: "declined", CalendarId: "primary", Start: now.Add(time.Minute * 5), ReminderMinutes: []int64{10}, ResponseStatus: CalendarResponse_Declined},
+		{Id: "started", CalendarId: "primary", Start: now.Add(-time.Minute), ReminderMinutes: []int64{10}},
+		{Id: "not-reminded", CalendarId: "team", Start: now.Add(time.Minute * 5), ReminderMinutes: []int64{10}},
+	}
+
+	for _, ev := range events {
+		c.scheduled = append(c.scheduled, scheduleCalendarReminders(ev)...)
+	}
+
+	c.checkDue(t.Context())
+
+	due := <-c.Reminders()
+	if len(due) != 1 || due[0].Event.Id != "soon" {
+		t.Fatalf("expected only the 10 minute reminder of the upcoming event on a reminded calendar, got %+v", due)
+	}
+
+	c.checkDue(t.Context())
+
+	select {
+	case due := <-c.Reminders():
+		t.Errorf("expected reminder to only be sent once, got %+v", due)
+	default:
+	}
+
+	// a restarted monitor remembers the reminders it sent
+	c, err = NewCalendarMonitor(nil, CalendarMonitorCfg{Calendars: []CalendarSelection{{Id: "primary", Remind: true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ev := range events {
+		c.scheduled = append(c.scheduled, scheduleCalendarReminders(ev)...)
+	}
+
+	c.checkDue(t.Context())
+
+	select {
+	case due := <-c.Reminders():
+		t.Errorf("expected reminder to not be sent again after a restart, got %+v", due)
+	default:
+	}
+}
+
+func TestCalendarMonitorNextEvent(t *testing.T) {
+	now := time.Now()
+	c := &CalendarMonitor{state: map[string]*calendarSyncState{
+		"primary": {Events: map[string]*CalendarEvent{
+			"over":     {Id: "over", Start: now.Add(-time.Hour * 2), End: now.Add(-time.Hour)},
+			"ongoing":  {Id: "ongoing", Start: now.Add(-time.Minute * 10), End: now.Add(time.Minute * 20)},
+			"all-day":  {Id: "all-day", Start: now.Add(-time.Hour), End: now.Add(time.Hour * 23), AllDay: true},
+			"declined": {Id: "declined", Start: now.Add(-time.Hour), End: now.Add(time.Hour), ResponseStatus: CalendarResponse_Declined},
+		}},
+		"work": {Events: map[string]*CalendarEvent{
+			"later": {Id: "later", Start: now.Add(time.Hour), End: now.Add(time.Hour * 2)},
+		}},
+	}}
+
+	if next := c.NextEvent(now); next == nil || next.Id != "ongoing" {
+		t.Errorf("expected the meeting in progress, got %+v", next)
+	}
+
+	if next := c.NextEvent(now.Add(time.Minute * 30)); next == nil || next.Id != "later" {
+		t.Errorf("expected the next meeting, got %+v", next)
+	}
+
+	if next := c.NextEvent(now.Add(time.Hour * 3)); next != nil {
+		t.Errorf("expected no meeting, got %+v", next)
+	}
+}
+
+func TestCalendarMonitorSetCalendars(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	c, err := NewCalendarMonitor(newTestCalendarService(t, &fakeCalendar{}), CalendarMonitorCfg{Calendars: []CalendarSelection{
+		{Id: "primary", Remind: true},
+		{Id: "team", Remind: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := c.ListCalendars(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || !entries[0].Primary || entries[1].Name != "Team on-call" {
+		t.Errorf("expected the primary and on-call calendars, got %+v", entries)
+	}
+
+	ev := &CalendarEvent{Id: "standup", CalendarId: "team", Start: time.Now().Add(time.Hour), ReminderMinutes: []int64{10}}
+	c.state["team"] = &calendarSyncState{Events: map[string]*CalendarEvent{ev.Id: ev}}
+	c.scheduled = scheduleCalendarReminders(ev)
+
+	err = c.SetCalendars([]CalendarSelection{{Id: "primary", Remind: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.state["team"]; ok || len(c.scheduled) != 0 {
+		t.Errorf("expected the events and reminders of the removed calendar to be dropped")
+	}
+
+	select {
+	case <-c.refreshChan:
+	default:
+		t.Errorf("expected a refresh to be requested")
+	}
+}
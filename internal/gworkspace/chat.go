@@ -0,0 +1,422 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/chat/v1"
+	"google.golang.org/api/people/v1"
+)
+
+const chatStateFilePath = "chat_state.json"
+
+// how often coalesced conversations are checked to see if they can be sent
+const chatFlushFreq = time.Second * 5
+
+const (
+	ChatSpaceType_Space         = "SPACE"
+	ChatSpaceType_GroupChat     = "GROUP_CHAT"
+	ChatSpaceType_DirectMessage = "DIRECT_MESSAGE"
+)
+
+// chatMentionAll is the user mentioned by @all
+const chatMentionAll = "users/all"
+
+type ChatMonitorCfg struct {
+	UpdateFreq time.Duration
+
+	// MutedSpaces are the resource names (spaces/...) or display names of
+	// spaces that are never notified
+	MutedSpaces []string
+
+	// CoalesceWindow is the shortest time between two notifications for the
+	// same space. messages that arrive sooner are held back and sent together
+	CoalesceWindow time.Duration
+}
+
+type ChatSpace struct {
+	// Name is the resource name of the space (spaces/...)
+	Name        string
+	DisplayName string
+
+	// Direct is set for direct messages and group chats without a name
+	Direct bool
+
+	// Uri opens the space in the browser
+	Uri string
+}
+
+type ChatMessage struct {
+	// Name is the resource name of the message (spaces/.../messages/...)
+	Name string
+
+	Sender     string
+	Text       string
+	CreateTime time.Time
+
+	// Mentioned is set if the message mentions the user, or everyone
+	Mentioned bool
+}
+
+// ChatConversation is the new messages of a space that are notified together
+type ChatConversation struct {
+	Space    ChatSpace
+	Messages []*ChatMessage
+}
+
+// chatState is persisted between polls
+type chatState struct {
+	// LastPoll is when the previous poll started. spaces that have not been
+	// seen before are read from then on
+	LastPoll time.Time
+
+	// Spaces are the create times of the newest message seen in each space
+	Spaces map[string]time.Time
+}
+
+// ChatMonitor polls google chat for direct messages and messages that mention
+// the user
+type ChatMonitor struct {
+	mu     sync.Mutex
+	svc    *chat.Service
+	people *people.Service
+	cfg    ChatMonitorCfg
+
+	// self is the resource name of the user (users/...)
+	self  string
+	names map[string]string
+
+	state *chatState
+
+	pending      map[string]*ChatConversation
+	lastNotified map[string]time.Time
+
+	conversationsChan chan []*ChatConversation
+}
+
+// NewChatMonitor creates a chat monitor. peopleSvc is used to find out who the
+// user is and the names of senders
+func NewChatMonitor(svc *chat.Service, peopleSvc *people.Service, cfg ChatMonitorCfg) (*ChatMonitor, error) {
+	state := &chatState{Spaces: make(map[string]time.Time)}
+	ok, err := readJsonFile(chatStateFilePath, state)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading chat state: %v", err)
+	}
+
+	if !ok {
+		state = nil
+	}
+
+	return &ChatMonitor{
+		svc:               svc,
+		people:            peopleSvc,
+		cfg:               cfg,
+		names:             make(map[string]string),
+		state:             state,
+		pending:           make(map[string]*ChatConversation),
+		lastNotified:      make(map[string]time.Time),
+		conversationsChan: make(chan []*ChatConversation, 32),
+	}, nil
+}
+
+// Conversations receives the conversations that should be notified. the
+// messages found by the very first poll are not notified
+func (c *ChatMonitor) Conversations() <-chan []*ChatConversation {
+	return c.conversationsChan
+}
+
+func (c *ChatMonitor) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.UpdateFreq)
+	defer ticker.Stop()
+
+	flushTicker := time.NewTicker(chatFlushFreq)
+	defer flushTicker.Stop()
+
+	poll := func() {
+		err := c.Poll(ctx)
+		if err != nil {
+			slog.Error("error while polling chat", "error", err)
+		}
+	}
+
+	poll()
+
+	for {
+		select {
+		case <-ticker.C:
+			poll()
+		case <-flushTicker.C:
+			c.flush(ctx, time.Now())
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Poll fetches the messages sent since the last poll in every space that has
+// been active, and queues the ones that should be notified
+func (c *ChatMonitor) Poll(ctx context.Context) error {
+	now := time.Now().Round(0)
+
+	if c.self == "" {
+		me, err := c.people.People.Get("people/me").PersonFields("metadata").Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error while fetching user: %w", err)
+		}
+
+		// chat user ids are the same as people ids
+		c.self = "users/" + strings.TrimPrefix(me.ResourceName, "people/")
+	}
+
+	c.mu.Lock()
+	prev := c.state
+	c.mu.Unlock()
+
+	if prev == nil {
+		slog.Info("polling chat for the first time, existing messages will not be notified")
+
+		return c.saveState(&chatState{LastPoll: now, Spaces: make(map[string]time.Time)})
+	}
+
+	state := &chatState{LastPoll: now, Spaces: make(map[string]time.Time, len(prev.Spaces))}
+
+	var errs error
+	err := c.svc.Spaces.List().Context(ctx).Pages(ctx, func(res *chat.ListSpacesResponse) error {
+		for _, s := range res.Spaces {
+			since, ok := prev.Spaces[s.Name]
+			if !ok {
+				since = prev.LastPoll
+			}
+
+			state.Spaces[s.Name] = since
+
+			active, err := time.Parse(time.RFC3339Nano, s.LastActiveTime)
+			if err == nil && !active.After(since) {
+				continue
+			}
+
+			space := newChatSpace(s)
+			if c.isMuted(space) {
+				// skip what was sent while muted, so that unmuting does not
+				// notify all of it
+				state.Spaces[s.Name] = now
+				if err == nil {
+					state.Spaces[s.Name] = active
+				}
+
+				continue
+			}
+
+			newest, msgs, err := c.fetchMessages(ctx, s, since)
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("error while fetching chat messages (space = %s): %w", s.Name, err))
+				continue
+			}
+
+			state.Spaces[s.Name] = newest
+
+			if len(msgs) > 0 {
+				c.queue(space, msgs)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while listing chat spaces: %w", err)
+	}
+
+	err = c.saveState(state)
+	if err != nil {
+		errs = errors.Join(errs, err)
+	}
+
+	c.flush(ctx, now)
+
+	return errs
+}
+
+// fetchMessages returns the messages in the space created after since that
+// should be notified, and the create time of the newest message
+func (c *ChatMonitor) fetchMessages(ctx context.Context, s *chat.Space, since time.Time) (time.Time, []*ChatMessage, error) {
+	newest := since
+	msgs := make([]*ChatMessage, 0)
+	direct := s.SpaceType == ChatSpaceType_DirectMessage || s.SpaceType == ChatSpaceType_GroupChat
+
+	err := c.svc.Spaces.Messages.List(s.Name).
+		Filter(fmt.Sprintf("createTime > %q", since.Format(time.RFC3339Nano))).
+		OrderBy("createTime asc").
+		Pages(ctx, func(res *chat.ListMessagesResponse) error {
+			for _, m := range res.Messages {
+				created, err := time.Parse(time.RFC3339Nano, m.CreateTime)
+				if err != nil {
+					slog.Warn("skipping chat message with invalid create time", "message", m.Name, "error", err)
+					continue
+				}
+
+				if created.After(newest) {
+					newest = created
+				}
+
+				if m.Sender == nil || m.Sender.Name == c.self {
+					continue
+				}
+
+				mentioned := isChatMentioned(m, c.self)
+				if !direct && !mentioned {
+					continue
+				}
+
+				msgs = append(msgs, &ChatMessage{
+					Name:       m.Name,
+					Sender:     c.userName(ctx, m.Sender),
+					Text:       m.Text,
+					CreateTime: created,
+					Mentioned:  mentioned,
+				})
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		return since, nil, err
+	}
+
+	return newest, msgs, nil
+}
+
+// queue adds messages to the pending conversation of the space
+func (c *ChatMonitor) queue(space ChatSpace, msgs []*ChatMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conv, ok := c.pending[space.Name]
+	if !ok {
+		conv = &ChatConversation{Space: space}
+		c.pending[space.Name] = conv
+	}
+
+	conv.Messages = append(conv.Messages, msgs...)
+}
+
+// flush sends the pending conversations of spaces that have not been notified
+// within the coalesce window
+func (c *ChatMonitor) flush(ctx context.Context, now time.Time) {
+	c.mu.Lock()
+
+	ready := make([]*ChatConversation, 0)
+	for name, conv := range c.pending {
+		if now.Sub(c.lastNotified[name]) < c.cfg.CoalesceWindow {
+			continue
+		}
+
+		ready = append(ready, conv)
+		c.lastNotified[name] = now
+		delete(c.pending, name)
+	}
+
+	for name, at := range c.lastNotified {
+		if now.Sub(at) >= c.cfg.CoalesceWindow {
+			delete(c.lastNotified, name)
+		}
+	}
+
+	c.mu.Unlock()
+
+	if len(ready) == 0 {
+		return
+	}
+
+	slices.SortFunc(ready, func(a, b *ChatConversation) int { return strings.Compare(a.Space.Name, b.Space.Name) })
+
+	select {
+	case c.conversationsChan <- ready:
+	case <-ctx.Done():
+	}
+}
+
+func (c *ChatMonitor) saveState(state *chatState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = state
+
+	err := writeJsonFile(chatStateFilePath, state)
+	if err != nil {
+		return fmt.Errorf("error while saving chat state: %v", err)
+	}
+
+	return nil
+}
+
+func (c *ChatMonitor) isMuted(space ChatSpace) bool {
+	return slices.ContainsFunc(c.cfg.MutedSpaces, func(m string) bool {
+		return m == space.Name || (space.DisplayName != "" && strings.EqualFold(m, space.DisplayName))
+	})
+}
+
+// userName returns the display name of a chat user. the api leaves it out for
+// people, so it is looked up with the people api and cached
+func (c *ChatMonitor) userName(ctx context.Context, u *chat.User) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+
+	c.mu.Lock()
+	name, ok := c.names[u.Name]
+	c.mu.Unlock()
+
+	if ok {
+		return name
+	}
+
+	// failed lookups are not cached so that they are tried again with the
+	// next message
+	p, err := c.people.People.Get("people/" + strings.TrimPrefix(u.Name, "users/")).PersonFields("names").Context(ctx).Do()
+	if err != nil {
+		slog.Warn("error while looking up chat sender", "user", u.Name, "error", err)
+		return "Someone"
+	}
+
+	name = "Someone"
+	if len(p.Names) > 0 {
+		name = p.Names[0].DisplayName
+	}
+
+	c.mu.Lock()
+	c.names[u.Name] = name
+	c.mu.Unlock()
+
+	return name
+}
+
+func newChatSpace(s *chat.Space) ChatSpace {
+	return ChatSpace{
+		Name:        s.Name,
+		DisplayName: s.DisplayName,
+		Direct:      s.SpaceType == ChatSpaceType_DirectMessage || s.SpaceType == ChatSpaceType_GroupChat,
+		Uri:         s.SpaceUri,
+	}
+}
+
+func isChatMentioned(m *chat.Message, self string) bool {
+	for _, a := range m.Annotations {
+		if a.Type != "USER_MENTION" || a.UserMention == nil || a.UserMention.User == nil {
+			continue
+		}
+
+		if a.UserMention.User.Name == self || a.UserMention.User.Name == chatMentionAll {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,268 @@
+package gworkspace_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"google.golang.org/api/chat/v1"
+	"google.golang.org/api/people/v1"
+)
+
+// fakeChat serves the spaces and messages of the chat api, and people for the
+// people api. the user is people/1. messages are filtered by the createTime in
+// the filter of the request
+type fakeChat struct {
+	spaces   []*chat.Space
+	messages map[string][]*chat.Message
+	people   map[string]string
+}
+
+func newTestChatServices(t *testing.T, f *fakeChat) (*chat.Service, *people.Service) {
+	opts := gworkspace.FakeApi(t, func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case path == "people/me":
+			json.NewEncoder(w).Encode(&people.Person{ResourceName: "people/1"})
+		case strings.HasPrefix(path, "people/"):
+			name, ok := f.people[path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			json.NewEncoder(w).Encode(&people.Person{ResourceName: path, Names: []*people.Name{{DisplayName: name}}})
+		case path == "spaces":
+			json.NewEncoder(w).Encode(&chat.ListSpacesResponse{Spaces: f.spaces})
+		case strings.HasSuffix(path, "/messages"):
+			filter := r.URL.Query().Get("filter")
+			since, err := time.Parse(time.RFC3339Nano, strings.Trim(strings.TrimPrefix(filter, "createTime > "), `"`))
+			if err != nil {
+				http.Error(w, "bad filter "+filter, http.StatusBadRequest)
+				return
+			}
+
+			msgs := slices.DeleteFunc(slices.Clone(f.messages[strings.TrimSuffix(path, "/messages")]), func(m *chat.Message) bool {
+				created, _ := time.Parse(time.RFC3339Nano, m.CreateTime)
+				return !created.After(since)
+			})
+
+			json.NewEncoder(w).Encode(&chat.ListMessagesResponse{Messages: msgs})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	chatSvc, err := chat.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peopleSvc, err := people.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return chatSvc, peopleSvc
+}
+
+func testChatMessage(space string, n int, sender string, at time.Time, mentions ...string) *chat.Message {
+	m := &chat.Message{
+		Name:       space + "/messages/" + strconv.Itoa(n),
+		Sender:     &chat.User{Name: sender, Type: "HUMAN"},
+		Text:       "message " + strconv.Itoa(n),
+		CreateTime: at.Format(time.RFC3339Nano),
+	}
+
+	for _, u := range mentions {
+		m.Annotations = append(m.Annotations, &chat.Annotation{
+			Type:        "USER_MENTION",
+			UserMention: &chat.UserMentionMetadata{Type: "MENTION", User: &chat.User{Name: u}},
+		})
+	}
+
+	return m
+}
+
+func receiveChatConversations(t *testing.T, c *gworkspace.ChatMonitor) map[string][]string {
+	t.Helper()
+
+	select {
+	case convs := <-c.Conversations():
+		texts := make(map[string][]string, len(convs))
+		for _, conv := range convs {
+			for _, m := range conv.Messages {
+				texts[conv.Space.Name] = append(texts[conv.Space.Name], m.Sender+": "+m.Text)
+			}
+		}
+
+		return texts
+	default:
+		return nil
+	}
+}
+
+func TestChatMonitorNotifiesDirectMessagesAndMentions(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	f := &fakeChat{people: map[string]string{"people/2": "Alice"}}
+
+	chatSvc, peopleSvc := newTestChatServices(t, f)
+	c, err := gworkspace.NewChatMonitor(chatSvc, peopleSvc, gworkspace.ChatMonitorCfg{MutedSpaces: []string{"Random"}, CoalesceWindow: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receiveChatConversations(t, c); got != nil {
+		t.Fatalf("expected the first poll to not notify, got %v", got)
+	}
+
+	at := time.Now().Add(time.Second)
+	f.spaces = []*chat.Space{
+		{Name: "spaces/dm", SpaceType: gworkspace.ChatSpaceType_DirectMessage, LastActiveTime: at.Add(time.Second).Format(time.RFC3339Nano)},
+		{Name: "spaces/team", DisplayName: "Team", SpaceType: gworkspace.ChatSpaceType_Space, LastActiveTime: at.Add(time.Second).Format(time.RFC3339Nano)},
+		{Name: "spaces/random", DisplayName: "Random", SpaceType: gworkspace.ChatSpaceType_Space, LastActiveTime: at.Format(time.RFC3339Nano)},
+	}
+	f.messages = map[string][]*chat.Message{
+		"spaces/dm": {
+			testChatMessage("spaces/dm", 1, "users/2", at),
+			testChatMessage("spaces/dm", 2, "users/1", at.Add(time.Second)),
+		},
+		"spaces/team": {
+			testChatMessage("spaces/team", 1, "users/2", at),
+			testChatMessage("spaces/team", 2, "users/2", at.Add(time.Second), "users/1"),
+		},
+		"spaces/random": {
+			testChatMessage("spaces/random", 1, "users/2", at, "users/all"),
+		},
+	}
+
+	err = c.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := receiveChatConversations(t, c)
+	want := map[string][]string{
+		"spaces/dm":   {"Alice: message 1"},
+		"spaces/team": {"Alice: message 2"},
+	}
+
+	if len(got) != len(want) || !slices.Equal(got["spaces/dm"], want["spaces/dm"]) || !slices.Equal(got["spaces/team"], want["spaces/team"]) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// a restarted monitor carries on from the saved state
+	c, err = gworkspace.NewChatMonitor(chatSvc, peopleSvc, gworkspace.ChatMonitorCfg{MutedSpaces: []string{"Random"}, CoalesceWindow: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receiveChatConversations(t, c); got != nil {
+		t.Fatalf("expected messages to be notified once, got %v", got)
+	}
+
+	// unmuting a space does not notify what was sent while it was muted
+	c, err = gworkspace.NewChatMonitor(chatSvc, peopleSvc, gworkspace.ChatMonitorCfg{CoalesceWindow: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receiveChatConversations(t, c); got != nil {
+		t.Fatalf("expected messages sent while muted to not be notified, got %v", got)
+	}
+}
+
+func TestChatMonitorCoalescesConversations(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	f := &fakeChat{people: map[string]string{"people/2": "Alice"}}
+
+	chatSvc, peopleSvc := newTestChatServices(t, f)
+	c, err := gworkspace.NewChatMonitor(chatSvc, peopleSvc, gworkspace.ChatMonitorCfg{CoalesceWindow: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Now().Add(time.Second)
+	f.spaces = []*chat.Space{{Name: "spaces/dm", SpaceType: gworkspace.ChatSpaceType_DirectMessage}}
+	f.messages = map[string][]*chat.Message{"spaces/dm": {testChatMessage("spaces/dm", 1, "users/2", at)}}
+
+	err = c.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receiveChatConversations(t, c); len(got["spaces/dm"]) != 1 {
+		t.Fatalf("expected the first message to be notified straight away, got %v", got)
+	}
+
+	f.messages["spaces/dm"] = append(f.messages["spaces/dm"],
+		testChatMessage("spaces/dm", 2, "users/2", at.Add(time.Second)),
+		testChatMessage("spaces/dm", 3, "users/2", at.Add(time.Second*2)),
+	)
+
+	err = c.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receiveChatConversations(t, c); got != nil {
+		t.Fatalf("expected messages within the coalesce window to be held back, got %v", got)
+	}
+
+	c.Flush(t.Context(), time.Now().Add(time.Minute))
+
+	got := receiveChatConversations(t, c)
+	if want := []string{"Alice: message 2", "Alice: message 3"}; !slices.Equal(got["spaces/dm"], want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestChatMonitorRetriesFailedNameLookups(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	f := &fakeChat{people: map[string]string{}}
+
+	chatSvc, peopleSvc := newTestChatServices(t, f)
+	c, err := gworkspace.NewChatMonitor(chatSvc, peopleSvc, gworkspace.ChatMonitorCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &chat.User{Name: "users/2"}
+	if name := c.UserName(t.Context(), u); name != "Someone" {
+		t.Fatalf("expected a failed lookup to fall back to Someone, got %q", name)
+	}
+
+	f.people["people/2"] = "Alice"
+
+	if name := c.UserName(t.Context(), u); name != "Alice" {
+		t.Fatalf("expected the lookup to be tried again, got %q", name)
+	}
+}
@@ -7,10 +7,10 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/gmail/v1"
 )
 
 const (
@@ -18,13 +18,26 @@ const (
 	tokenFilePath       = "token.json"
 )
 
+// cachedToken is the format of the token file. the scopes the token was
+// granted for are stored alongside it so that a new token is requested
+// when the required scopes change
+type cachedToken struct {
+	*oauth2.Token
+	Scopes []string `json:"scopes"`
+}
+
 type HttpClient struct {
 	*http.Client
+
+	quota *GmailQuota
 }
 
-func NewHttpClient() *HttpClient {
+// NewHttpClient creates a client whose gmail requests are counted against
+// quota. quota may be nil
+func NewHttpClient(quota *GmailQuota) *HttpClient {
 	return &HttpClient{
 		Client: &http.Client{},
+		quota:  quota,
 	}
 }
 
@@ -34,7 +47,7 @@ func (c *HttpClient) Configure(ctx context.Context, scopes ...string) error {
 		return fmt.Errorf("error while reading credentials files (%s): %v", credentialsFilePath, err)
 	}
 
-	cfg, err := google.ConfigFromJSON(b, gmail.GmailReadonlyScope)
+	cfg, err := google.ConfigFromJSON(b, scopes...)
 	if err != nil {
 		return fmt.Errorf("error while configuring oauth: %v", err)
 	}
@@ -46,11 +59,15 @@ func (c *HttpClient) Configure(ctx context.Context, scopes ...string) error {
 
 	c.Client = cfg.Client(ctx, tok)
 
+	if c.quota != nil {
+		c.Client.Transport = c.quota.Transport(c.Client.Transport)
+	}
+
 	return nil
 }
 
 func getToken(ctx context.Context, cfg *oauth2.Config) (*oauth2.Token, error) {
-	tok, err := getCachedToken()
+	tok, err := getCachedToken(cfg.Scopes)
 	if err != nil {
 		slog.Warn("failed to get cached token", "file", tokenFilePath, "error", err)
 
@@ -59,7 +76,7 @@ func getToken(ctx context.Context, cfg *oauth2.Config) (*oauth2.Token, error) {
 			return nil, fmt.Errorf("failed to get token new token: %v", err)
 		}
 
-		err = setCachedToken(tok)
+		err = setCachedToken(tok, cfg.Scopes)
 		if err != nil {
 			// This error is okay because we will just get a new token next time
 			slog.Error("failed to set cached token", "error", err)
@@ -68,7 +85,7 @@ func getToken(ctx context.Context, cfg *oauth2.Config) (*oauth2.Token, error) {
 	return tok, nil
 }
 
-func getCachedToken() (*oauth2.Token, error) {
+func getCachedToken(scopes []string) (*oauth2.Token, error) {
 	slog.Debug("getting cached token from file", "file", tokenFilePath)
 
 	f, err := os.Open(tokenFilePath)
@@ -77,17 +94,27 @@ func getCachedToken() (*oauth2.Token, error) {
 	}
 	defer f.Close()
 
-	tok := &oauth2.Token{}
+	tok := &cachedToken{}
 	err = json.NewDecoder(f).Decode(tok)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token file (%s): %v", tokenFilePath, err)
 	}
 
-	return tok, nil
+	if tok.Token == nil {
+		return nil, fmt.Errorf("token file (%s) does not contain a token", tokenFilePath)
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(tok.Scopes, scope) {
+			return nil, fmt.Errorf("cached token was not granted scope %s", scope)
+		}
+	}
+
+	return tok.Token, nil
 }
 
-func setCachedToken(token *oauth2.Token) error {
+func setCachedToken(token *oauth2.Token, scopes []string) error {
 	slog.Debug("caching token in file", "file", tokenFilePath)
 
 	f, err := os.OpenFile(tokenFilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
@@ -96,7 +123,7 @@ func setCachedToken(token *oauth2.Token) error {
 	}
 	defer f.Close()
 
-	err = json.NewEncoder(f).Encode(token)
+	err = json.NewEncoder(f).Encode(cachedToken{Token: token, Scopes: scopes})
 	if err != nil {
 		return fmt.Errorf("failed to write token file (%s): %v", tokenFilePath, err)
 	}
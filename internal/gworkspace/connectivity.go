@@ -0,0 +1,76 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// address dialed to check whether the google apis can be reached
+const connectivityProbeAddr = "gmail.googleapis.com:443"
+
+const (
+	connectivityProbeTimeout = time.Second * 3
+	connectivityProbeFreq    = time.Second * 2
+)
+
+// WaitForConnectivity blocks until the google apis can be reached or ctx is
+// done. after a resume or network change the connection usually takes a few
+// seconds to come up, and checking before then fails
+func WaitForConnectivity(ctx context.Context) error {
+	for {
+		err := probeConnectivity(ctx)
+		if err == nil {
+			return nil
+		}
+
+		slog.Debug("google apis are not reachable yet", "error", err)
+
+		select {
+		case <-time.After(connectivityProbeFreq):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// probeConnectivity opens and closes a connection to the google apis. it is
+// much cheaper than a request and uses no quota
+func probeConnectivity(ctx context.Context) error {
+	d := net.Dialer{Timeout: connectivityProbeTimeout}
+
+	conn, err := d.DialContext(ctx, "tcp", connectivityProbeAddr)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// IsNetworkError reports whether err was caused by the network rather than by
+// the api rejecting the request
+func IsNetworkError(err error) bool {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		return false
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// the connection was dropped part way through the response
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
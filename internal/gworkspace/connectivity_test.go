@@ -0,0 +1,71 @@
+package gworkspace
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestIsNetworkError(t *testing.T) {
+	dnsErr := &url.Error{Op: "Get", URL: "https://gmail.googleapis.com", Err: &net.DNSError{Err: "no such host", Name: "gmail.googleapis.com"}}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "dns", err: fmt.Errorf("error while fetching: %w", dnsErr), expected: true},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, expected: true},
+		{name: "api error", err: fmt.Errorf("error while fetching: %w", &googleapi.Error{Code: 500}), expected: false},
+		{name: "other", err: errors.New("something else"), expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := IsNetworkError(test.err); actual != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGmailMonitorGoesOfflineOnce(t *testing.T) {
+	m := NewGmailMonitor(nil, GmailMonitorCfg{OfflineAfter: 3})
+	netErr := &net.OpError{Op: "dial", Err: errors.New("network is unreachable")}
+
+	m.updateConnectivity(netErr)
+	m.updateConnectivity(&googleapi.Error{Code: 503})
+	m.updateConnectivity(netErr)
+	m.updateConnectivity(netErr)
+
+	if m.IsOffline() {
+		t.Fatalf("expected api errors to reset the count of network failures")
+	}
+
+	m.updateConnectivity(netErr)
+	m.updateConnectivity(netErr)
+
+	if !m.IsOffline() {
+		t.Fatalf("expected monitor to be offline")
+	}
+
+	if online := <-m.Online(); online {
+		t.Errorf("expected offline to be published")
+	}
+
+	select {
+	case <-m.Online():
+		t.Errorf("expected going offline to be published once")
+	default:
+	}
+
+	m.updateConnectivity(nil)
+
+	if online := <-m.Online(); !online || m.IsOffline() {
+		t.Errorf("expected monitor to be online again")
+	}
+}
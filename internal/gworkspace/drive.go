@@ -0,0 +1,258 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const driveStateFilePath = "drive_state.json"
+
+var driveSharedFileFields = googleapi.Field("nextPageToken,files(id,name,mimeType,webViewLink,sharedWithMeTime,owners(displayName,emailAddress),sharingUser(displayName,emailAddress))")
+
+// errDriveListDone stops paging once files older than the high water mark are
+// reached
+var errDriveListDone = errors.New("reached files that were already seen")
+
+type DriveMonitorCfg struct {
+	UpdateFreq time.Duration
+
+	// SharedWithMe notifies about files that are shared with the user
+	SharedWithMe bool
+
+	// Comments notifies about comments that mention the user or assign them
+	// an action item
+	Comments bool
+}
+
+// DriveFile is a file that was shared with the user
+type DriveFile struct {
+	Id       string
+	Name     string
+	MimeType string
+
+	// Owner and SharedBy are the display names, or email addresses if there is
+	// no display name. SharedBy is empty if drive does not know who shared it
+	Owner    string
+	SharedBy string
+
+	WebViewLink string
+	SharedTime  time.Time
+}
+
+type driveState struct {
+	// SharedWithMe is the newest shared with me time that has been seen
+	SharedWithMe time.Time
+
+	// Comments is when comments were last polled. comments created after it
+	// are new
+	Comments time.Time
+}
+
+// DriveMonitor polls drive for files that are shared with the user and for
+// comments that mention them
+type DriveMonitor struct {
+	mu    sync.Mutex
+	svc   *drive.Service
+	cfg   DriveMonitorCfg
+	state *driveState
+
+	// email is the email address of the user, used to find mentions
+	email string
+
+	sharedChan   chan []*DriveFile
+	commentsChan chan []*DriveComment
+}
+
+func NewDriveMonitor(svc *drive.Service, cfg DriveMonitorCfg) (*DriveMonitor, error) {
+	state := &driveState{}
+	_, err := readJsonFile(driveStateFilePath, state)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading drive state: %v", err)
+	}
+
+	return &DriveMonitor{
+		svc:          svc,
+		cfg:          cfg,
+		state:        state,
+		sharedChan:   make(chan []*DriveFile, 32),
+		commentsChan: make(chan []*DriveComment, 32),
+	}, nil
+}
+
+// Shared receives the files that were shared with the user since the last
+// poll, oldest first. files shared before the very first poll are not sent
+func (d *DriveMonitor) Shared() <-chan []*DriveFile {
+	return d.sharedChan
+}
+
+func (d *DriveMonitor) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(d.cfg.UpdateFreq)
+	defer ticker.Stop()
+
+	poll := func() {
+		err := d.Poll(ctx)
+		if err != nil {
+			slog.Error("error while polling drive", "error", err)
+		}
+	}
+
+	poll()
+
+	for {
+		select {
+		case <-ticker.C:
+			poll()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Poll checks for newly shared files and new comments, whichever are enabled
+func (d *DriveMonitor) Poll(ctx context.Context) error {
+	var errs error
+
+	if d.cfg.SharedWithMe {
+		err := d.pollShared(ctx)
+		if err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+
+	if d.cfg.Comments {
+		err := d.pollComments(ctx)
+		if err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// pollShared lists the files shared with the user after the high water mark
+func (d *DriveMonitor) pollShared(ctx context.Context) error {
+	d.mu.Lock()
+	mark := d.state.SharedWithMe
+	d.mu.Unlock()
+
+	first := mark.IsZero()
+
+	files := make([]*DriveFile, 0)
+	newest := mark
+
+	err := d.svc.Files.List().
+		Q("sharedWithMe").
+		OrderBy("sharedWithMeTime desc").
+		Fields(driveSharedFileFields).
+		Context(ctx).
+		Pages(ctx, func(res *drive.FileList) error {
+			for _, f := range res.Files {
+				shared, err := time.Parse(time.RFC3339Nano, f.SharedWithMeTime)
+				if err != nil {
+					slog.Warn("skipping shared file with invalid shared time", "fileId", f.Id, "error", err)
+					continue
+				}
+
+				if !shared.After(mark) {
+					return errDriveListDone
+				}
+
+				if shared.After(newest) {
+					newest = shared
+				}
+
+				// the first poll only finds the high water mark
+				if first {
+					return errDriveListDone
+				}
+
+				files = append(files, newDriveFile(f, shared))
+			}
+
+			return nil
+		})
+
+	if err != nil && !errors.Is(err, errDriveListDone) {
+		return fmt.Errorf("error while listing shared files: %w", err)
+	}
+
+	if first {
+		slog.Info("polling shared drive files for the first time, files shared before now will not be notified")
+
+		// nothing is shared yet, so anything shared from now on is new
+		if newest.IsZero() {
+			newest = time.Now()
+		}
+	}
+
+	err = d.saveState(func(s *driveState) { s.SharedWithMe = newest })
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+
+	slices.Reverse(files)
+
+	select {
+	case d.sharedChan <- files:
+	case <-ctx.Done():
+	}
+
+	return nil
+}
+
+// saveState applies update to a copy of the state and saves it
+func (d *DriveMonitor) saveState(update func(s *driveState)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	state := *d.state
+	update(&state)
+	d.state = &state
+
+	err := writeJsonFile(driveStateFilePath, state)
+	if err != nil {
+		return fmt.Errorf("error while saving drive state: %v", err)
+	}
+
+	return nil
+}
+
+func newDriveFile(f *drive.File, shared time.Time) *DriveFile {
+	file := &DriveFile{
+		Id:          f.Id,
+		Name:        f.Name,
+		MimeType:    f.MimeType,
+		WebViewLink: f.WebViewLink,
+		SharedTime:  shared,
+	}
+
+	if len(f.Owners) > 0 {
+		file.Owner = driveUserName(f.Owners[0])
+	}
+
+	if f.SharingUser != nil {
+		file.SharedBy = driveUserName(f.SharingUser)
+	}
+
+	return file
+}
+
+func driveUserName(u *drive.User) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+
+	return u.EmailAddress
+}
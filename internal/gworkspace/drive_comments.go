@@ -0,0 +1,235 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const driveFolderMimeType = "application/vnd.google-apps.folder"
+
+var driveModifiedFileFields = googleapi.Field("nextPageToken,files(id,name,webViewLink)")
+
+var driveCommentFields = googleapi.Field("nextPageToken,comments(id,content,createdTime,deleted,resolved,assigneeEmailAddress,mentionedEmailAddresses,author(displayName,emailAddress,me),quotedFileContent(value),replies(id,content,createdTime,deleted,action,assigneeEmailAddress,mentionedEmailAddresses,author(displayName,emailAddress,me)))")
+
+// DriveComment is a comment, or a reply to one, that mentions the user or
+// assigns them an action item
+type DriveComment struct {
+	FileId   string
+	FileName string
+
+	CommentId string
+
+	// ReplyId is set if it is a reply to the comment
+	ReplyId string
+
+	Author  string
+	Content string
+
+	// Quote is the text of the file the comment is anchored to, if any
+	Quote string
+
+	// Assigned is set if the comment assigns an action item to the user,
+	// otherwise the user is only mentioned
+	Assigned bool
+
+	// Link opens the discussion in the file
+	Link string
+
+	CreatedTime time.Time
+}
+
+// Comments receives the comments that mention the user or assign them an
+// action item since the last poll, oldest first. comments made before the
+// very first poll are not sent
+func (d *DriveMonitor) Comments() <-chan []*DriveComment {
+	return d.commentsChan
+}
+
+// pollComments looks for new comments on the files that were modified since
+// the last poll, since adding a comment modifies the file
+func (d *DriveMonitor) pollComments(ctx context.Context) error {
+	now := time.Now().Round(0)
+
+	d.mu.Lock()
+	since := d.state.Comments
+	d.mu.Unlock()
+
+	if since.IsZero() {
+		slog.Info("polling drive comments for the first time, existing comments will not be notified")
+
+		return d.saveState(func(s *driveState) { s.Comments = now })
+	}
+
+	if d.email == "" {
+		about, err := d.svc.About.Get().Fields("user(emailAddress)").Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error while fetching drive user: %w", err)
+		}
+
+		d.email = about.User.EmailAddress
+	}
+
+	files := make([]*drive.File, 0)
+	err := d.svc.Files.List().
+		Q(fmt.Sprintf("modifiedTime > '%s' and trashed = false and mimeType != '%s'", since.UTC().Format(time.RFC3339), driveFolderMimeType)).
+		Fields(driveModifiedFileFields).
+		Context(ctx).
+		Pages(ctx, func(res *drive.FileList) error {
+			files = append(files, res.Files...)
+			return nil
+		})
+
+	if err != nil {
+		return fmt.Errorf("error while listing modified files: %w", err)
+	}
+
+	var errs error
+	comments := make([]*DriveComment, 0)
+	for _, f := range files {
+		found, err := d.fileComments(ctx, f, since, now)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("error while listing comments (fileId = %s): %w", f.Id, err))
+			continue
+		}
+
+		comments = append(comments, found...)
+	}
+
+	// files that failed are not retried, so that a file whose comments cannot
+	// be read does not hold back every other file
+	err = d.saveState(func(s *driveState) { s.Comments = now })
+	if err != nil {
+		return errors.Join(errs, err)
+	}
+
+	if len(comments) == 0 {
+		return errs
+	}
+
+	slices.SortFunc(comments, func(a, b *DriveComment) int { return a.CreatedTime.Compare(b.CreatedTime) })
+
+	select {
+	case d.commentsChan <- comments:
+	case <-ctx.Done():
+	}
+
+	return errs
+}
+
+// fileComments returns the comments and replies on a file created in the
+// interval (since, until] that mention the user or assign them an action item
+func (d *DriveMonitor) fileComments(ctx context.Context, f *drive.File, since, until time.Time) ([]*DriveComment, error) {
+	found := make([]*DriveComment, 0)
+
+	isNew := func(created string) (time.Time, bool) {
+		t, err := time.Parse(time.RFC3339Nano, created)
+		if err != nil {
+			return t, false
+		}
+
+		return t, t.After(since) && !t.After(until)
+	}
+
+	err := d.svc.Comments.List(f.Id).
+		StartModifiedTime(since.UTC().Format(time.RFC3339Nano)).
+		Fields(driveCommentFields).
+		Context(ctx).
+		Pages(ctx, func(res *drive.CommentList) error {
+			for _, c := range res.Comments {
+				if c.Deleted {
+					continue
+				}
+
+				quote := ""
+				if c.QuotedFileContent != nil {
+					quote = c.QuotedFileContent.Value
+				}
+
+				if created, ok := isNew(c.CreatedTime); ok && !c.Resolved {
+					assigned, mentioned := d.isForUser(c.Author, c.AssigneeEmailAddress, c.MentionedEmailAddresses)
+					if assigned || mentioned {
+						found = append(found, &DriveComment{
+							FileId:      f.Id,
+							FileName:    f.Name,
+							CommentId:   c.Id,
+							Author:      driveUserName(c.Author),
+							Content:     c.Content,
+							Quote:       quote,
+							Assigned:    assigned,
+							Link:        driveDiscussionLink(f.WebViewLink, c.Id),
+							CreatedTime: created,
+						})
+					}
+				}
+
+				for _, r := range c.Replies {
+					created, ok := isNew(r.CreatedTime)
+					if r.Deleted || !ok {
+						continue
+					}
+
+					assigned, mentioned := d.isForUser(r.Author, r.AssigneeEmailAddress, r.MentionedEmailAddresses)
+					if !assigned && !mentioned {
+						continue
+					}
+
+					found = append(found, &DriveComment{
+						FileId:      f.Id,
+						FileName:    f.Name,
+						CommentId:   c.Id,
+						ReplyId:     r.Id,
+						Author:      driveUserName(r.Author),
+						Content:     r.Content,
+						Quote:       quote,
+						Assigned:    assigned,
+						Link:        driveDiscussionLink(f.WebViewLink, c.Id),
+						CreatedTime: created,
+					})
+				}
+			}
+
+			return nil
+		})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// isForUser reports whether a comment by author assigns the user an action
+// item or mentions them. comments by the user themselves are ignored
+func (d *DriveMonitor) isForUser(author *drive.User, assignee string, mentioned []string) (bool, bool) {
+	if author == nil || author.Me || strings.EqualFold(author.EmailAddress, d.email) {
+		return false, false
+	}
+
+	isUser := func(email string) bool { return strings.EqualFold(email, d.email) }
+
+	return isUser(assignee), slices.ContainsFunc(mentioned, isUser)
+}
+
+// driveDiscussionLink links to a comment in a file. docs editors open the
+// discussion given by the disco parameter
+func driveDiscussionLink(webViewLink, commentId string) string {
+	u, err := url.Parse(webViewLink)
+	if err != nil || webViewLink == "" {
+		return webViewLink
+	}
+
+	q := u.Query()
+	q.Set("disco", commentId)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
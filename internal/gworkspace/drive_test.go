@@ -0,0 +1,207 @@
+package gworkspace_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"google.golang.org/api/drive/v3"
+)
+
+// fakeDrive serves the files shared with the user, newest first, and the
+// comments of modified files. the user is me@example.com
+type fakeDrive struct {
+	shared   []*drive.File
+	modified []*drive.File
+	comments map[string][]*drive.Comment
+}
+
+func newTestDriveService(t *testing.T, f *fakeDrive) *drive.Service {
+	opts := gworkspace.FakeApi(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/about":
+			json.NewEncoder(w).Encode(&drive.About{User: &drive.User{EmailAddress: "me@example.com"}})
+		case r.URL.Path == "/files":
+			q := r.URL.Query().Get("q")
+			switch {
+			case q == "sharedWithMe":
+				json.NewEncoder(w).Encode(&drive.FileList{Files: f.shared})
+			case strings.HasPrefix(q, "modifiedTime > "):
+				json.NewEncoder(w).Encode(&drive.FileList{Files: f.modified})
+			default:
+				http.Error(w, "unexpected query "+q, http.StatusBadRequest)
+			}
+		case strings.HasSuffix(r.URL.Path, "/comments"):
+			id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/files/"), "/comments")
+			json.NewEncoder(w).Encode(&drive.CommentList{Comments: f.comments[id]})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	svc, err := drive.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return svc
+}
+
+func testSharedFile(id, name string, shared time.Time) *drive.File {
+	return &drive.File{
+		Id:               id,
+		Name:             name,
+		MimeType:         "application/vnd.google-apps.document",
+		WebViewLink:      "https://docs.google.com/document/d/" + id,
+		SharedWithMeTime: shared.Format(time.RFC3339Nano),
+		Owners:           []*drive.User{{DisplayName: "Alice", EmailAddress: "alice@example.com"}},
+		SharingUser:      &drive.User{EmailAddress: "bob@example.com"},
+	}
+}
+
+func TestDriveMonitorNotifiesNewlySharedFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	now := time.Now().Truncate(time.Second)
+	f := &fakeDrive{shared: []*drive.File{testSharedFile("old", "Old doc", now.Add(-time.Hour))}}
+
+	d, err := gworkspace.NewDriveMonitor(newTestDriveService(t, f), gworkspace.DriveMonitorCfg{SharedWithMe: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case files := <-d.Shared():
+		t.Fatalf("expected the first poll to not notify, got %d files", len(files))
+	default:
+	}
+
+	f.shared = append([]*drive.File{
+		testSharedFile("b", "Budget", now.Add(time.Minute*2)),
+		testSharedFile("a", "Agenda", now.Add(time.Minute)),
+	}, f.shared...)
+
+	// a restarted monitor carries on from the saved high water mark
+	d, err = gworkspace.NewDriveMonitor(newTestDriveService(t, f), gworkspace.DriveMonitorCfg{SharedWithMe: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []*gworkspace.DriveFile
+	select {
+	case files = <-d.Shared():
+	default:
+		t.Fatal("expected newly shared files")
+	}
+
+	if len(files) != 2 || files[0].Id != "a" || files[1].Id != "b" {
+		t.Fatalf("expected files a and b oldest first, got %v", files)
+	}
+
+	if files[0].Owner != "Alice" || files[0].SharedBy != "bob@example.com" || files[0].WebViewLink == "" {
+		t.Fatalf("unexpected file %+v", files[0])
+	}
+
+	err = d.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case files := <-d.Shared():
+		t.Fatalf("expected files to be notified once, got %d files", len(files))
+	default:
+	}
+}
+
+func TestDriveMonitorNotifiesCommentsForUser(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	f := &fakeDrive{}
+
+	d, err := gworkspace.NewDriveMonitor(newTestDriveService(t, f), gworkspace.DriveMonitorCfg{Comments: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	at := time.Now().Format(time.RFC3339Nano)
+	old := time.Now().Add(-time.Hour).Format(time.RFC3339Nano)
+	alice := &drive.User{DisplayName: "Alice", EmailAddress: "alice@example.com"}
+	me := &drive.User{DisplayName: "Me", EmailAddress: "me@example.com", Me: true}
+
+	f.modified = []*drive.File{{Id: "doc", Name: "Plan", WebViewLink: "https://docs.google.com/document/d/doc/edit?usp=drivesdk"}}
+	f.comments = map[string][]*drive.Comment{"doc": {
+		{Id: "mention", Author: alice, Content: "+me@example.com thoughts?", CreatedTime: at, MentionedEmailAddresses: []string{"me@example.com"}, QuotedFileContent: &drive.CommentQuotedFileContent{Value: "budget"}},
+		{Id: "other", Author: alice, Content: "+bob@example.com", CreatedTime: at, MentionedEmailAddresses: []string{"bob@example.com"}},
+		{Id: "mine", Author: me, Content: "+me@example.com note to self", CreatedTime: at, MentionedEmailAddresses: []string{"me@example.com"}},
+		{Id: "old", Author: alice, CreatedTime: old, Replies: []*drive.Reply{
+			{Id: "assign", Author: alice, Content: "over to you", CreatedTime: at, AssigneeEmailAddress: "ME@example.com", MentionedEmailAddresses: []string{"me@example.com"}},
+			{Id: "stale", Author: alice, Content: "old", CreatedTime: old, MentionedEmailAddresses: []string{"me@example.com"}},
+		}},
+	}}
+
+	err = d.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var comments []*gworkspace.DriveComment
+	select {
+	case comments = <-d.Comments():
+	default:
+		t.Fatal("expected new comments")
+	}
+
+	if len(comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(comments))
+	}
+
+	got := make(map[string]*gworkspace.DriveComment, len(comments))
+	for _, c := range comments {
+		got[c.CommentId+"/"+c.ReplyId] = c
+	}
+
+	mention := got["mention/"]
+	if mention == nil || mention.Assigned || mention.Quote != "budget" || mention.Author != "Alice" {
+		t.Fatalf("unexpected mention %+v", mention)
+	}
+
+	if want := "https://docs.google.com/document/d/doc/edit?disco=mention&usp=drivesdk"; mention.Link != want {
+		t.Fatalf("expected link %s, got %s", want, mention.Link)
+	}
+
+	if assign := got["old/assign"]; assign == nil || !assign.Assigned {
+		t.Fatalf("expected the reply to assign an action item, got %+v", assign)
+	}
+
+	err = d.Poll(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case comments := <-d.Comments():
+		t.Fatalf("expected comments to be notified once, got %d", len(comments))
+	default:
+	}
+}
@@ -0,0 +1,46 @@
+package gworkspace
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/chat/v1"
+	"google.golang.org/api/option"
+)
+
+// FakeApi serves handler in place of the google apis until the test ends. the
+// options point a service at it. responses are json unless handler says
+// otherwise
+func FakeApi(t *testing.T, handler http.HandlerFunc) []option.ClientOption {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return []option.ClientOption{option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL)}
+}
+
+const AlertConsoleUrl = alertConsoleUrl
+
+// the monitors check what is due on a ticker. these let tests check at a
+// given time instead of waiting for it
+
+func (c *ChatMonitor) Flush(ctx context.Context, now time.Time) {
+	c.flush(ctx, now)
+}
+
+func (m *TasksMonitor) CheckDue(ctx context.Context, now time.Time) {
+	m.checkDue(ctx, now)
+}
+
+func (t *GmailFollowUpTracker) CheckDue(ctx context.Context) {
+	t.checkDue(ctx)
+}
+
+func (c *ChatMonitor) UserName(ctx context.Context, u *chat.User) string {
+	return c.userName(ctx, u)
+}
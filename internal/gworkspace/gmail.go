@@ -2,6 +2,7 @@ package gworkspace
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -14,6 +15,9 @@ import (
 	"google.golang.org/api/googleapi"
 )
 
+// how often connectivity is probed while offline
+const gmailOfflineProbeFreq = time.Second * 15
+
 type GmailHistoryId struct {
 	id      uint64
 	isValid bool
@@ -55,41 +59,124 @@ func (h *GmailHistoryId) Clear() {
 }
 
 type GmailMessage struct {
+	Id       string
+	ThreadId string
+	LabelIds []string
+
 	To      string
 	From    string
 	Subject string
+
+	// Date is when gmail received or sent the message, or zero if unknown
+	Date time.Time
+
+	// Alias is the lower cased alias or group address the message was
+	// delivered to, or empty if it was delivered to the primary address
+	Alias string
+
+	Attachments []*GmailAttachment
+}
+
+type GmailMonitorCfg struct {
+	UpdateFreq time.Duration
+
+	// Labels are the ids of the labels that are watched for new messages and
+	// whose unread counts are tracked
+	Labels []string
+
+	// UnreadRefreshFreq is how often the unread counts are fetched from gmail.
+	// in between they are kept up to date from the history
+	UnreadRefreshFreq time.Duration
+
+	// Aliases are addresses the user receives mail for in addition to their
+	// send-as addresses, such as groups they are a member of
+	Aliases []string
+
+	// OfflineAfter is the number of consecutive checks that have to fail
+	// because of the network before the monitor goes offline. while offline,
+	// scheduled checks are replaced by cheap connectivity probes
+	OfflineAfter int
+
+	// Quota is checked before every scheduled check, which is skipped if it
+	// would exceed the budget. may be nil
+	Quota *GmailQuota
+
+	// FetchAttachments requests the MIME part structure of new messages
+	// (without bodies) so that attachments can be listed
+	FetchAttachments bool
+}
+
+// GmailHistoryHandler is given every history record seen by a GmailMonitor
+type GmailHistoryHandler interface {
+	HandleGmailHistory(ctx context.Context, history []*gmail.History) error
 }
 
 type GmailMonitor struct {
 	mu  sync.Mutex
 	svc *gmail.Service
+	cfg GmailMonitorCfg
 
 	isInitialized bool
 	historyId     *GmailHistoryId
-	updateFreq    time.Duration
 
-	msgsChan chan []*GmailMessage
+	aliases []string
+
+	unreadCounts      map[string]*GmailLabelCounts
+	unreadRefreshedAt time.Time
+
+	historyHandlers []GmailHistoryHandler
+
+	networkFailures int
+	offline         bool
+
+	msgsChan         chan []*GmailMessage
+	unreadCountsChan chan []GmailLabelCounts
+	onlineChan       chan bool
 }
 
-func NewGmailMonitor(svc *gmail.Service, updateFreq time.Duration) *GmailMonitor {
+func NewGmailMonitor(svc *gmail.Service, cfg GmailMonitorCfg) *GmailMonitor {
 	return &GmailMonitor{
 		svc: svc,
+		cfg: cfg,
 
 		isInitialized: false,
 		historyId:     NewGmailHistoryId(),
-		updateFreq:    updateFreq,
 
-		msgsChan: make(chan []*GmailMessage, 32),
+		unreadCounts: make(map[string]*GmailLabelCounts),
+
+		msgsChan:         make(chan []*GmailMessage, 32),
+		unreadCountsChan: make(chan []GmailLabelCounts, 1),
+		onlineChan:       make(chan bool, 1),
 	}
 }
 
+// AddHistoryHandler registers h to be called with the history records found on
+// every check. handlers are called while the monitor is locked and must not
+// call back into it
+func (g *GmailMonitor) AddHistoryHandler(h GmailHistoryHandler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.historyHandlers = append(g.historyHandlers, h)
+}
+
 func (g *GmailMonitor) Initialize(ctx context.Context) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
 	err := g.refreshHistoryId(ctx)
 	if err != nil {
-		return fmt.Errorf("error while fetching latest history id: %v", err)
+		return fmt.Errorf("error while fetching latest history id: %w", err)
+	}
+
+	err = g.refreshUnreadCounts(ctx)
+	if err != nil {
+		return fmt.Errorf("error while fetching unread counts: %w", err)
+	}
+
+	err = g.refreshAliases(ctx)
+	if err != nil {
+		return fmt.Errorf("error while fetching aliases: %w", err)
 	}
 
 	g.isInitialized = true
@@ -98,17 +185,48 @@ func (g *GmailMonitor) Initialize(ctx context.Context) error {
 }
 
 func (g *GmailMonitor) Watch(ctx context.Context) error {
-	ticker := time.NewTicker(g.updateFreq)
+	ticker := time.NewTicker(g.cfg.UpdateFreq)
+	defer ticker.Stop()
+
+	probeTicker := time.NewTicker(gmailOfflineProbeFreq)
+	defer probeTicker.Stop()
 
 	tick := func() {
+		if g.IsOffline() {
+			// probe instead until gmail can be reached again
+			return
+		}
+
+		if g.OverQuota() {
+			slog.Warn("gmail quota budget reached, skipping check for new messages")
+			return
+		}
+
 		slog.Debug("GmailMonitor Watch checking for new messages")
 
 		err := g.CheckNow(ctx)
+		logCheckError(err)
+
+		slog.Debug("GmailMonitor Watch waiting before checking again", "duration", g.cfg.UpdateFreq)
+	}
+
+	probe := func() {
+		if !g.IsOffline() {
+			return
+		}
+
+		err := probeConnectivity(ctx)
 		if err != nil {
-			slog.Error("error while checking for new messages", "error", err)
+			slog.Debug("gmail is still unreachable", "error", err)
+			return
 		}
 
-		slog.Debug("GmailMonitor Watch waiting before checking again", "duration", g.updateFreq)
+		// the history id is kept while offline, so this picks up everything
+		// that was missed
+		slog.Info("gmail is reachable again, checking for messages missed while offline")
+
+		err = g.CheckNow(ctx)
+		logCheckError(err)
 	}
 
 	slog.Debug("starting GmailMonitor ticker")
@@ -120,6 +238,8 @@ func (g *GmailMonitor) Watch(ctx context.Context) error {
 		select {
 		case <-ticker.C:
 			tick()
+		case <-probeTicker.C:
+			probe()
 		case <-ctx.Done():
 			return nil
 		}
@@ -130,29 +250,52 @@ func (g *GmailMonitor) CheckNow(ctx context.Context) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	err := g.checkNow(ctx)
+	g.updateConnectivity(err)
+
+	return err
+}
+
+// errors are wrapped with %w so that network errors can be told apart from
+// api errors. checkNow must be called with g.mu held
+func (g *GmailMonitor) checkNow(ctx context.Context) error {
 	slog.Debug("checking for new messages")
 
 	msgs, err := g.fetchNewMessages(ctx)
 
 	// 404 when history id is invalid
-	if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == http.StatusNotFound {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusNotFound {
 		slog.Debug("gmail responded 404 when fetching new messages. refreshing history id and trying again")
 
 		err := g.refreshHistoryId(ctx)
 		if err != nil {
-			return fmt.Errorf("error while refreshing history id: %v", err)
+			return fmt.Errorf("error while refreshing history id: %w", err)
 		}
 
+		// changes between the old and new history id are lost, so the
+		// counts can no longer be trusted
+		g.unreadRefreshedAt = time.Time{}
+
 		msgs, err = g.fetchNewMessages(ctx)
 		if err != nil {
-			return fmt.Errorf("error while fetching new messages: %v", err)
+			return fmt.Errorf("error while fetching new messages: %w", err)
 		}
 	}
 
 	if err != nil {
-		return fmt.Errorf("error while fetching new messages: %v", err)
+		return fmt.Errorf("error while fetching new messages: %w", err)
 	}
 
+	if time.Since(g.unreadRefreshedAt) >= g.cfg.UnreadRefreshFreq {
+		err := g.refreshUnreadCounts(ctx)
+		if err != nil {
+			slog.Error("error while refreshing unread counts, continuing with counts from history", "error", err)
+		}
+	}
+
+	g.publishUnreadCounts(ctx)
+
 	if len(msgs) > 0 {
 		slog.Info("received new messages from gmail", "numMessages", len(msgs))
 		for _, msg := range msgs {
@@ -172,6 +315,88 @@ func (g *GmailMonitor) Messages() <-chan []*GmailMessage {
 	return g.msgsChan
 }
 
+// UnreadCounts receives the latest unread counts of the watched labels after
+// every check. only the most recent counts are kept if they are not received
+func (g *GmailMonitor) UnreadCounts() <-chan []GmailLabelCounts {
+	return g.unreadCountsChan
+}
+
+// Online receives whether gmail can be reached whenever that changes. only the
+// most recent value is kept if it is not received
+func (g *GmailMonitor) Online() <-chan bool {
+	return g.onlineChan
+}
+
+func (g *GmailMonitor) IsOffline() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.offline
+}
+
+// OverQuota reports whether checking for new messages would exceed the quota
+// budget. checks that are not scheduled should be skipped too
+func (g *GmailMonitor) OverQuota() bool {
+	return g.cfg.Quota != nil && g.cfg.Quota.WouldExceed(gmailCheckQuotaUnits)
+}
+
+// updateConnectivity goes offline after too many consecutive network errors,
+// and back online after any check that reached gmail, even if gmail returned
+// an error. must be called with g.mu held
+func (g *GmailMonitor) updateConnectivity(err error) {
+	if err != nil && IsNetworkError(err) {
+		g.networkFailures++
+
+		if !g.offline && g.networkFailures >= max(g.cfg.OfflineAfter, 1) {
+			slog.Warn("gmail is unreachable, going offline", "consecutiveFailures", g.networkFailures)
+			g.setOffline(true)
+		}
+
+		return
+	}
+
+	g.networkFailures = 0
+
+	if g.offline {
+		slog.Info("gmail is reachable, going online")
+		g.setOffline(false)
+	}
+}
+
+// setOffline must be called with g.mu held
+func (g *GmailMonitor) setOffline(offline bool) {
+	g.offline = offline
+
+	select {
+	case <-g.onlineChan:
+	default:
+	}
+
+	g.onlineChan <- !offline
+}
+
+func logCheckError(err error) {
+	if err == nil {
+		return
+	}
+
+	if IsNetworkError(err) {
+		slog.Warn("network error while checking for new messages", "error", err)
+	} else {
+		slog.Error("error while checking for new messages", "error", err)
+	}
+}
+
+func (g *GmailMonitor) isWatched(labelIds []string) bool {
+	for _, l := range g.cfg.Labels {
+		if slices.Contains(labelIds, l) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (g *GmailMonitor) fetchNewMessages(ctx context.Context) ([]*GmailMessage, error) {
 	if !g.isInitialized || !g.historyId.IsValid() {
 		panic("attempted to check for messages, but GmailMonitor was not initialized. call Initialize() first")
@@ -180,6 +405,7 @@ func (g *GmailMonitor) fetchNewMessages(ctx context.Context) ([]*GmailMessage, e
 	slog.Debug("fetching new messages from gmail")
 
 	msgIds := make([]string, 0)
+	records := make([]*gmail.History, 0)
 
 	forEachPage := func(res *gmail.ListHistoryResponse) error {
 		if res.HistoryId > g.historyId.GetId() {
@@ -189,21 +415,35 @@ func (g *GmailMonitor) fetchNewMessages(ctx context.Context) ([]*GmailMessage, e
 
 		for _, h := range res.History {
 			for _, m := range h.MessagesAdded {
-				msgIds = append(msgIds, m.Message.Id)
+				if g.isWatched(m.Message.LabelIds) {
+					msgIds = append(msgIds, m.Message.Id)
+				}
 			}
+
+			g.applyUnreadHistory(h)
 		}
 
+		records = append(records, res.History...)
+
 		return nil
 	}
 
 	err := g.svc.Users.History.List("me").
 		StartHistoryId(g.historyId.GetId()).
-		HistoryTypes("messageAdded").
-		LabelId("INBOX").
+		HistoryTypes("messageAdded", "messageDeleted", "labelAdded", "labelRemoved").
 		Pages(ctx, forEachPage)
 
 	if err != nil {
-		return []*GmailMessage{}, fmt.Errorf("error while fetching history from gmail (last history id = %d): %v", g.historyId.GetId(), err)
+		return []*GmailMessage{}, fmt.Errorf("error while fetching history from gmail (last history id = %d): %w", g.historyId.GetId(), err)
+	}
+
+	if len(records) > 0 {
+		for _, h := range g.historyHandlers {
+			err := h.HandleGmailHistory(ctx, records)
+			if err != nil {
+				slog.Error("error while handling gmail history", "error", err)
+			}
+		}
 	}
 
 	group, ctx := errgroup.WithContext(ctx)
@@ -213,28 +453,48 @@ func (g *GmailMonitor) fetchNewMessages(ctx context.Context) ([]*GmailMessage, e
 
 	for i, id := range msgIds {
 		group.Go(func() error {
-			res, err := g.svc.Users.Messages.Get("me", id).
-				Context(ctx).
-				Format("metadata").
-				MetadataHeaders("To", "From", "Subject").
-				Do()
+			call := g.svc.Users.Messages.Get("me", id).
+				Context(ctx)
 
+			if g.cfg.FetchAttachments {
+				call = call.Format("full").Fields(gmailMessageStructureFields)
+			} else {
+				call = call.Format("metadata").MetadataHeaders("To", "Cc", "From", "Subject", "Delivered-To", "X-Original-To")
+			}
+
+			res, err := call.Do()
 			if err != nil {
 				return fmt.Errorf("error while fetching metadata for message (message id = %s): %v", id, err)
 			}
 
-			msg := &GmailMessage{}
+			msg := &GmailMessage{
+				Id:          res.Id,
+				ThreadId:    res.ThreadId,
+				LabelIds:    res.LabelIds,
+				Date:        gmailInternalDate(res.InternalDate),
+				Attachments: collectGmailAttachments(res.Payload),
+			}
+			deliveredTo := make([]string, 0)
+			recipients := make([]string, 0)
+
 			for _, h := range res.Payload.Headers {
 				switch h.Name {
 				case "To":
 					msg.To = h.Value
+					recipients = append(recipients, h.Value)
+				case "Cc":
+					recipients = append(recipients, h.Value)
 				case "From":
 					msg.From = h.Value
 				case "Subject":
 					msg.Subject = h.Value
+				case "Delivered-To", "X-Original-To":
+					deliveredTo = append(deliveredTo, h.Value)
 				}
 			}
 
+			msg.Alias = resolveGmailAlias(g.aliases, deliveredTo, recipients)
+
 			msgs[i] = msg
 
 			return nil
@@ -253,13 +513,23 @@ func (g *GmailMonitor) fetchNewMessages(ctx context.Context) ([]*GmailMessage, e
 	return msgs, nil
 }
 
+// gmailInternalDate converts the internal date of a message, in milliseconds
+// since the epoch, to a time. zero if it is not set
+func gmailInternalDate(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(ms)
+}
+
 func (g *GmailMonitor) refreshHistoryId(ctx context.Context) error {
 	res, err := g.svc.Users.GetProfile("me").
 		Context(ctx).
 		Do()
 
 	if err != nil {
-		return fmt.Errorf("error getting profile from Gmail: %v", err)
+		return fmt.Errorf("error getting profile from Gmail: %w", err)
 	}
 
 	g.historyId.SetId(res.HistoryId)
@@ -0,0 +1,267 @@
+package gworkspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+const mutedThreadsFilePath = "muted_threads.json"
+
+const (
+	GmailLabel_Inbox   = "INBOX"
+	GmailLabel_Unread  = "UNREAD"
+	GmailLabel_Starred = "STARRED"
+	GmailLabel_Trash   = "TRASH"
+	GmailLabel_Sent    = "SENT"
+	GmailLabel_Draft   = "DRAFT"
+
+	// GmailLabel_Muted is not a real gmail label. the api has no concept of
+	// muting, so muted threads are tracked locally and marked with this
+	// label in our own copy of the message state
+	GmailLabel_Muted = "MUTED"
+)
+
+type GmailAction string
+
+const (
+	GmailAction_MarkRead    GmailAction = "mark_read"
+	GmailAction_MarkUnread  GmailAction = "mark_unread"
+	GmailAction_Archive     GmailAction = "archive"
+	GmailAction_MoveToInbox GmailAction = "move_to_inbox"
+	GmailAction_Star        GmailAction = "star"
+	GmailAction_Unstar      GmailAction = "unstar"
+	GmailAction_Trash       GmailAction = "trash"
+	GmailAction_Untrash     GmailAction = "untrash"
+	GmailAction_Mute        GmailAction = "mute"
+	GmailAction_Unmute      GmailAction = "unmute"
+)
+
+var AllGmailActions = []GmailAction{
+	GmailAction_MarkRead,
+	GmailAction_MarkUnread,
+	GmailAction_Archive,
+	GmailAction_MoveToInbox,
+	GmailAction_Star,
+	GmailAction_Unstar,
+	GmailAction_Trash,
+	GmailAction_Untrash,
+	GmailAction_Mute,
+	GmailAction_Unmute,
+}
+
+func ParseGmailAction(s string) (GmailAction, error) {
+	a := GmailAction(s)
+	if !slices.Contains(AllGmailActions, a) {
+		return "", fmt.Errorf("unknown gmail action %q", s)
+	}
+
+	return a, nil
+}
+
+// Title is the text shown to the user for the action
+func (a GmailAction) Title() string {
+	switch a {
+	case GmailAction_MarkRead:
+		return "Mark as read"
+	case GmailAction_MarkUnread:
+		return "Mark as unread"
+	case GmailAction_Archive:
+		return "Archive"
+	case GmailAction_MoveToInbox:
+		return "Move to inbox"
+	case GmailAction_Star:
+		return "Star"
+	case GmailAction_Unstar:
+		return "Unstar"
+	case GmailAction_Trash:
+		return "Trash"
+	case GmailAction_Untrash:
+		return "Untrash"
+	case GmailAction_Mute:
+		return "Mute thread"
+	case GmailAction_Unmute:
+		return "Unmute thread"
+	}
+
+	return string(a)
+}
+
+// LabelChanges returns the labels that are added to and removed from a message
+// when the action is applied
+func (a GmailAction) LabelChanges() (add []string, remove []string) {
+	switch a {
+	case GmailAction_MarkRead:
+		return nil, []string{GmailLabel_Unread}
+	case GmailAction_MarkUnread:
+		return []string{GmailLabel_Unread}, nil
+	case GmailAction_Archive:
+		return nil, []string{GmailLabel_Inbox}
+	case GmailAction_MoveToInbox:
+		return []string{GmailLabel_Inbox}, nil
+	case GmailAction_Star:
+		return []string{GmailLabel_Starred}, nil
+	case GmailAction_Unstar:
+		return nil, []string{GmailLabel_Starred}
+	case GmailAction_Trash:
+		return []string{GmailLabel_Trash}, nil
+	case GmailAction_Untrash:
+		return nil, []string{GmailLabel_Trash}
+	case GmailAction_Mute:
+		return []string{GmailLabel_Muted}, []string{GmailLabel_Inbox}
+	case GmailAction_Unmute:
+		// like gmail, unmuting leaves the thread wherever it is
+		return nil, []string{GmailLabel_Muted}
+	}
+
+	panic(fmt.Sprintf("unknown gmail action %q", a))
+}
+
+// AppliesTo reports whether applying the action to a message with the given
+// labels would change anything
+func (a GmailAction) AppliesTo(labels []string) bool {
+	add, remove := a.LabelChanges()
+
+	for _, l := range add {
+		if !slices.Contains(labels, l) {
+			return true
+		}
+	}
+
+	for _, l := range remove {
+		if slices.Contains(labels, l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GmailActions modifies messages and threads on behalf of the user. requires
+// the gmail.modify scope
+type GmailActions struct {
+	mu  sync.Mutex
+	svc *gmail.Service
+
+	mutedThreads map[string]struct{}
+}
+
+func NewGmailActions(svc *gmail.Service) (*GmailActions, error) {
+	threadIds := make([]string, 0)
+	_, err := readJsonFile(mutedThreadsFilePath, &threadIds)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading muted threads: %v", err)
+	}
+
+	mutedThreads := make(map[string]struct{}, len(threadIds))
+	for _, id := range threadIds {
+		mutedThreads[id] = struct{}{}
+	}
+
+	return &GmailActions{
+		svc:          svc,
+		mutedThreads: mutedThreads,
+	}, nil
+}
+
+// ApplyToMessage applies the action to a single message. muting always applies
+// to the whole thread the message belongs to
+func (a *GmailActions) ApplyToMessage(ctx context.Context, action GmailAction, msg *GmailMessage) error {
+	slog.Debug("applying gmail action to message", "action", action, "messageId", msg.Id)
+	ctx = withGmailUserAction(ctx)
+
+	var err error
+	switch action {
+	case GmailAction_Trash:
+		_, err = a.svc.Users.Messages.Trash("me", msg.Id).Context(ctx).Do()
+	case GmailAction_Untrash:
+		_, err = a.svc.Users.Messages.Untrash("me", msg.Id).Context(ctx).Do()
+	case GmailAction_Mute, GmailAction_Unmute:
+		return a.ApplyToThread(ctx, action, msg.ThreadId)
+	default:
+		add, remove := action.LabelChanges()
+		_, err = a.svc.Users.Messages.Modify("me", msg.Id, &gmail.ModifyMessageRequest{
+			AddLabelIds:    add,
+			RemoveLabelIds: remove,
+		}).Context(ctx).Do()
+	}
+
+	if err != nil {
+		return fmt.Errorf("error while applying action %s to message (message id = %s): %v", action, msg.Id, err)
+	}
+
+	return nil
+}
+
+// ApplyToThread applies the action to every message in the thread
+func (a *GmailActions) ApplyToThread(ctx context.Context, action GmailAction, threadId string) error {
+	slog.Debug("applying gmail action to thread", "action", action, "threadId", threadId)
+	ctx = withGmailUserAction(ctx)
+
+	var err error
+	switch action {
+	case GmailAction_Trash:
+		_, err = a.svc.Users.Threads.Trash("me", threadId).Context(ctx).Do()
+	case GmailAction_Untrash:
+		_, err = a.svc.Users.Threads.Untrash("me", threadId).Context(ctx).Do()
+	default:
+		add, remove := action.LabelChanges()
+		add = slices.DeleteFunc(add, func(l string) bool { return l == GmailLabel_Muted })
+		remove = slices.DeleteFunc(remove, func(l string) bool { return l == GmailLabel_Muted })
+
+		if len(add) > 0 || len(remove) > 0 {
+			_, err = a.svc.Users.Threads.Modify("me", threadId, &gmail.ModifyThreadRequest{
+				AddLabelIds:    add,
+				RemoveLabelIds: remove,
+			}).Context(ctx).Do()
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("error while applying action %s to thread (thread id = %s): %v", action, threadId, err)
+	}
+
+	switch action {
+	case GmailAction_Mute:
+		err = a.setThreadMuted(threadId, true)
+	case GmailAction_Unmute:
+		err = a.setThreadMuted(threadId, false)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error while saving muted threads: %v", err)
+	}
+
+	return nil
+}
+
+func (a *GmailActions) IsThreadMuted(threadId string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, ok := a.mutedThreads[threadId]
+	return ok
+}
+
+func (a *GmailActions) setThreadMuted(threadId string, muted bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if muted {
+		a.mutedThreads[threadId] = struct{}{}
+	} else {
+		delete(a.mutedThreads, threadId)
+	}
+
+	threadIds := make([]string, 0, len(a.mutedThreads))
+	for id := range a.mutedThreads {
+		threadIds = append(threadIds, id)
+	}
+	slices.Sort(threadIds)
+
+	return writeJsonFile(mutedThreadsFilePath, threadIds)
+}
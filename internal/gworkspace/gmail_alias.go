@@ -0,0 +1,75 @@
+package gworkspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// refreshAliases fetches the send-as addresses of the user. must be called
+// with g.mu held
+func (g *GmailMonitor) refreshAliases(ctx context.Context) error {
+	profile, err := g.svc.Users.GetProfile("me").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting profile from Gmail: %w", err)
+	}
+
+	res, err := g.svc.Users.Settings.SendAs.List("me").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error while listing send-as addresses: %w", err)
+	}
+
+	aliases := make([]string, 0, len(res.SendAs)+len(g.cfg.Aliases))
+	for _, a := range slices.Concat(g.cfg.Aliases, sendAsAddresses(res.SendAs)) {
+		a = strings.ToLower(a)
+		if a != strings.ToLower(profile.EmailAddress) && !slices.Contains(aliases, a) {
+			aliases = append(aliases, a)
+		}
+	}
+
+	slog.Debug("fetched aliases", "primary", profile.EmailAddress, "aliases", aliases)
+
+	g.aliases = aliases
+
+	return nil
+}
+
+// resolveGmailAlias determines which of the user's aliases a message was
+// addressed to, or returns an empty string for the primary address. the
+// delivery headers are checked first since they also name the address for
+// bcc'd and group mail, then the visible recipients. aliases must be lower
+// case
+func resolveGmailAlias(aliases []string, deliveredTo []string, recipients []string) string {
+	for _, headers := range [][]string{deliveredTo, recipients} {
+		for _, h := range headers {
+			addrs, err := mail.ParseAddressList(h)
+			if err != nil {
+				slog.Debug("failed to parse address header while resolving alias", "value", h, "error", err)
+				continue
+			}
+
+			for _, addr := range addrs {
+				a := strings.ToLower(addr.Address)
+				if slices.Contains(aliases, a) {
+					return a
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func sendAsAddresses(sendAs []*gmail.SendAs) []string {
+	addrs := make([]string, len(sendAs))
+	for i, s := range sendAs {
+		addrs[i] = s.SendAsEmail
+	}
+
+	return addrs
+}
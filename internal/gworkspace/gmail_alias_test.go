@@ -0,0 +1,48 @@
+package gworkspace
+
+import "testing"
+
+func TestResolveGmailAlias(t *testing.T) {
+	aliases := []string{"oncall@example.com", "me.alias@example.com"}
+
+	tests := []struct {
+		name        string
+		deliveredTo []string
+		recipients  []string
+		expected    string
+	}{
+		{
+			name:        "primary address",
+			deliveredTo: []string{"me@example.com"},
+			recipients:  []string{"Me <me@example.com>"},
+			expected:    "",
+		},
+		{
+			name:        "group in delivery headers",
+			deliveredTo: []string{"me@example.com", "OnCall@example.com"},
+			recipients:  []string{"Someone Else <else@example.com>"},
+			expected:    "oncall@example.com",
+		},
+		{
+			name:        "alias in recipients",
+			deliveredTo: []string{"me@example.com"},
+			recipients:  []string{"Someone Else <else@example.com>, Me <me.alias@example.com>"},
+			expected:    "me.alias@example.com",
+		},
+		{
+			name:        "unparseable header",
+			deliveredTo: []string{"not an address"},
+			recipients:  []string{"oncall@example.com"},
+			expected:    "oncall@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alias := resolveGmailAlias(aliases, tt.deliveredTo, tt.recipients)
+			if alias != tt.expected {
+				t.Errorf("expected alias %q, got %q", tt.expected, alias)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package gworkspace
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+)
+
+// maximum depth of nested MIME parts requested when fetching the message
+// structure. attachments nested deeper than this are not listed
+const gmailMaxPartDepth = 5
+
+// maximum number of attachments named in a summary before the remainder
+// are collapsed into a count
+const gmailMaxSummaryAttachments = 3
+
+// gmailMessageStructureFields selects the headers and MIME part structure of a
+// message, leaving out all part bodies so they are never downloaded
+var gmailMessageStructureFields = googleapi.Field("id,threadId,labelIds,internalDate,payload(" + gmailPartFields(gmailMaxPartDepth) + ")")
+
+type GmailAttachment struct {
+	Filename string
+	MimeType string
+	Size     int64
+}
+
+func (a *GmailAttachment) String() string {
+	return fmt.Sprintf("%s (%s)", a.Filename, formatByteSize(a.Size))
+}
+
+// AttachmentSummary returns a compact, human readable summary of the attachments
+// on the message (e.g. "invoice.pdf (240 KB), +2 more") or an empty string if
+// there are none
+func (m *GmailMessage) AttachmentSummary() string {
+	if len(m.Attachments) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, gmailMaxSummaryAttachments+1)
+	for i, a := range m.Attachments {
+		if i == gmailMaxSummaryAttachments {
+			names = append(names, fmt.Sprintf("+%d more", len(m.Attachments)-i))
+			break
+		}
+
+		names = append(names, a.String())
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func gmailPartFields(depth int) string {
+	fields := "headers,filename,mimeType,body/size"
+	if depth > 1 {
+		fields += ",parts(" + gmailPartFields(depth-1) + ")"
+	}
+
+	return fields
+}
+
+func collectGmailAttachments(part *gmail.MessagePart) []*GmailAttachment {
+	attachments := make([]*GmailAttachment, 0)
+
+	var walk func(p *gmail.MessagePart)
+	walk = func(p *gmail.MessagePart) {
+		if p == nil {
+			return
+		}
+
+		if p.Filename != "" {
+			a := &GmailAttachment{
+				Filename: p.Filename,
+				MimeType: p.MimeType,
+			}
+
+			if p.Body != nil {
+				a.Size = p.Body.Size
+			}
+
+			attachments = append(attachments, a)
+		}
+
+		for _, child := range p.Parts {
+			walk(child)
+		}
+	}
+
+	walk(part)
+
+	return attachments
+}
+
+func formatByteSize(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	v := float64(n) / float64(div)
+	if v < 10 {
+		return fmt.Sprintf("%.1f %cB", v, "KMGT"[exp])
+	}
+
+	return fmt.Sprintf("%.0f %cB", v, "KMGT"[exp])
+}
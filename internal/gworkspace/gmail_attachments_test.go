@@ -0,0 +1,121 @@
+package gworkspace
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+func TestCollectGmailAttachments(t *testing.T) {
+	tests := []struct {
+		name     string
+		part     *gmail.MessagePart
+		expected []string
+	}{
+		{
+			name:     "no payload",
+			part:     nil,
+			expected: []string{},
+		},
+		{
+			name:     "plain text",
+			part:     &gmail.MessagePart{MimeType: "text/plain", Body: &gmail.MessagePartBody{Size: 12}},
+			expected: []string{},
+		},
+		{
+			name: "nested multipart",
+			part: &gmail.MessagePart{MimeType: "multipart/mixed", Parts: []*gmail.MessagePart{
+				{MimeType: "multipart/alternative", Parts: []*gmail.MessagePart{
+					{MimeType: "text/plain"},
+					{MimeType: "text/html"},
+				}},
+				{MimeType: "application/pdf", Filename: "invoice.pdf", Body: &gmail.MessagePartBody{Size: 240_000}},
+				{MimeType: "multipart/mixed", Parts: []*gmail.MessagePart{
+					{MimeType: "image/png", Filename: "chart.png", Body: &gmail.MessagePartBody{Size: 1_500_000}},
+				}},
+			}},
+			expected: []string{"invoice.pdf (240 KB)", "chart.png (1.5 MB)"},
+		},
+		{
+			name: "inline parts without a filename",
+			part: &gmail.MessagePart{MimeType: "multipart/related", Parts: []*gmail.MessagePart{
+				{MimeType: "text/html"},
+				{MimeType: "image/png", Body: &gmail.MessagePartBody{Size: 2000}},
+				{MimeType: "text/calendar", Filename: "invite.ics"},
+			}},
+			expected: []string{"invite.ics (0 B)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, a := range collectGmailAttachments(tt.part) {
+				got = append(got, a.String())
+			}
+
+			if !slices.Equal(got, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGmailMessageAttachmentSummary(t *testing.T) {
+	attachment := func(name string, size int64) *GmailAttachment {
+		return &GmailAttachment{Filename: name, Size: size}
+	}
+
+	tests := []struct {
+		name        string
+		attachments []*GmailAttachment
+		expected    string
+	}{
+		{name: "none", attachments: nil, expected: ""},
+		{name: "one", attachments: []*GmailAttachment{attachment("a.pdf", 999)}, expected: "a.pdf (999 B)"},
+		{
+			name:        "at the limit",
+			attachments: []*GmailAttachment{attachment("a", 1), attachment("b", 2), attachment("c", 3)},
+			expected:    "a (1 B), b (2 B), c (3 B)",
+		},
+		{
+			name:        "over the limit",
+			attachments: []*GmailAttachment{attachment("a", 1), attachment("b", 2), attachment("c", 3), attachment("d", 4), attachment("e", 5)},
+			expected:    "a (1 B), b (2 B), c (3 B), +2 more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &GmailMessage{Attachments: tt.attachments}
+			if got := msg.AttachmentSummary(); got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected string
+	}{
+		{size: 0, expected: "0 B"},
+		{size: 999, expected: "999 B"},
+		{size: 1000, expected: "1.0 KB"},
+		{size: 9_949, expected: "9.9 KB"},
+		{size: 10_000, expected: "10 KB"},
+		{size: 999_000, expected: "999 KB"},
+		{size: 1_000_000, expected: "1.0 MB"},
+		{size: 25_000_000, expected: "25 MB"},
+		{size: 3_200_000_000, expected: "3.2 GB"},
+		{size: 1_000_000_000_000, expected: "1.0 TB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatByteSize(tt.size); got != tt.expected {
+			t.Errorf("formatByteSize(%d): expected %q, got %q", tt.size, tt.expected, got)
+		}
+	}
+}
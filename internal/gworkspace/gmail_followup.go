@@ -0,0 +1,308 @@
+package gworkspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/gmail/v1"
+)
+
+const followUpsFilePath = "followups.json"
+
+// how often pending follow ups are checked. reminders are compared against
+// the wall clock so a reminder that fell due while suspended fires on the
+// first check after resuming
+const followUpCheckFreq = time.Minute
+
+// GmailFollowUp is a sent message that is waiting for a reply from someone else
+type GmailFollowUp struct {
+	ThreadId  string
+	MessageId string
+
+	To      string
+	Subject string
+
+	SentAt time.Time
+	DueAt  time.Time
+}
+
+type GmailFollowUpTrackerCfg struct {
+	// After is how long to wait for a reply before reminding
+	After time.Duration
+
+	// Label and Keyword limit tracking to sent messages with the label or with
+	// the keyword in their subject. all sent messages are tracked if both are
+	// empty
+	Label   string
+	Keyword string
+}
+
+// GmailFollowUpTracker reminds the user about sent messages that got no reply.
+// it is fed history records by a GmailMonitor and persists pending follow ups
+// so they survive restarts
+type GmailFollowUpTracker struct {
+	mu  sync.Mutex
+	svc *gmail.Service
+	cfg GmailFollowUpTrackerCfg
+
+	followUps map[string]*GmailFollowUp
+
+	remindersChan chan []*GmailFollowUp
+}
+
+var _ GmailHistoryHandler = (*GmailFollowUpTracker)(nil)
+
+func NewGmailFollowUpTracker(svc *gmail.Service, cfg GmailFollowUpTrackerCfg) (*GmailFollowUpTracker, error) {
+	followUps := make(map[string]*GmailFollowUp)
+	_, err := readJsonFile(followUpsFilePath, &followUps)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading follow ups: %v", err)
+	}
+
+	return &GmailFollowUpTracker{
+		svc:           svc,
+		cfg:           cfg,
+		followUps:     followUps,
+		remindersChan: make(chan []*GmailFollowUp, 32),
+	}, nil
+}
+
+// Reminders receives follow ups whose reply is overdue. each follow up is
+// only sent once
+func (t *GmailFollowUpTracker) Reminders() <-chan []*GmailFollowUp {
+	return t.remindersChan
+}
+
+// FollowUps returns the pending follow ups ordered by when they are due
+func (t *GmailFollowUpTracker) FollowUps() []GmailFollowUp {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	followUps := make([]GmailFollowUp, 0, len(t.followUps))
+	for _, f := range t.followUps {
+		followUps = append(followUps, *f)
+	}
+
+	slices.SortFunc(followUps, func(a, b GmailFollowUp) int { return a.DueAt.Compare(b.DueAt) })
+
+	return followUps
+}
+
+func (t *GmailFollowUpTracker) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(followUpCheckFreq)
+	defer ticker.Stop()
+
+	for {
+		t.checkDue(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (t *GmailFollowUpTracker) HandleGmailHistory(ctx context.Context, history []*gmail.History) error {
+	// fetch the subjects of sent messages up front, then apply the records in
+	// order so that a reply in the same batch as the sent message is noticed
+	sentIds := make([]string, 0)
+	for _, h := range history {
+		for _, m := range h.MessagesAdded {
+			if slices.Contains(m.Message.LabelIds, GmailLabel_Sent) {
+				sentIds = append(sentIds, m.Message.Id)
+			}
+		}
+
+		for _, m := range h.LabelsAdded {
+			if t.isOptInLabelAdded(m) {
+				sentIds = append(sentIds, m.Message.Id)
+			}
+		}
+	}
+
+	msgs, err := t.fetchSentMessages(ctx, sentIds)
+
+	sent := make(map[string]*GmailMessage, len(msgs))
+	for _, msg := range msgs {
+		sent[msg.Id] = msg
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changed := false
+	for _, h := range history {
+		for _, m := range h.MessagesAdded {
+			labels := m.Message.LabelIds
+
+			if slices.Contains(labels, GmailLabel_Sent) {
+				if msg, ok := sent[m.Message.Id]; ok && t.isTracked(msg) {
+					t.track(msg)
+					changed = true
+				}
+
+				continue
+			}
+
+			if slices.Contains(labels, GmailLabel_Draft) {
+				continue
+			}
+
+			// anything else added to the thread is a reply from someone else
+			if f, ok := t.followUps[m.Message.ThreadId]; ok {
+				slog.Debug("reply received, cancelling follow up", "threadId", f.ThreadId, "subject", f.Subject)
+				delete(t.followUps, m.Message.ThreadId)
+				changed = true
+			}
+		}
+
+		// sent messages can be opted in after sending by labelling them
+		for _, m := range h.LabelsAdded {
+			if msg, ok := sent[m.Message.Id]; ok && t.isOptInLabelAdded(m) {
+				t.track(msg)
+				changed = true
+			}
+		}
+	}
+
+	if changed {
+		if serr := t.save(); serr != nil {
+			err = fmt.Errorf("error while saving follow ups: %v", serr)
+		}
+	}
+
+	return err
+}
+
+func (t *GmailFollowUpTracker) isOptInLabelAdded(m *gmail.HistoryLabelAdded) bool {
+	return t.cfg.Label != "" && slices.Contains(m.LabelIds, t.cfg.Label) && slices.Contains(m.Message.LabelIds, GmailLabel_Sent)
+}
+
+// track must be called with t.mu held
+func (t *GmailFollowUpTracker) track(msg *GmailMessage) {
+	// sending again in the same thread restarts the wait
+	slog.Debug("tracking sent message for follow up", "threadId", msg.ThreadId, "subject", msg.Subject)
+
+	// the wait starts when the message was sent, not when it was seen, which
+	// can be much later after a restart or while offline. the monotonic
+	// reading is stripped so that DueAt is compared against the wall clock,
+	// which keeps running while suspended
+	sentAt := msg.Date
+	if sentAt.IsZero() {
+		sentAt = time.Now().Round(0)
+	}
+
+	t.followUps[msg.ThreadId] = &GmailFollowUp{
+		ThreadId:  msg.ThreadId,
+		MessageId: msg.Id,
+		To:        msg.To,
+		Subject:   msg.Subject,
+		SentAt:    sentAt,
+		DueAt:     sentAt.Add(t.cfg.After),
+	}
+}
+
+func (t *GmailFollowUpTracker) isTracked(msg *GmailMessage) bool {
+	if t.cfg.Label == "" && t.cfg.Keyword == "" {
+		return true
+	}
+
+	if t.cfg.Label != "" && slices.Contains(msg.LabelIds, t.cfg.Label) {
+		return true
+	}
+
+	return t.cfg.Keyword != "" && strings.Contains(strings.ToLower(msg.Subject), strings.ToLower(t.cfg.Keyword))
+}
+
+func (t *GmailFollowUpTracker) fetchSentMessages(ctx context.Context, ids []string) ([]*GmailMessage, error) {
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(16)
+
+	msgs := make([]*GmailMessage, len(ids))
+
+	for i, id := range ids {
+		group.Go(func() error {
+			res, err := t.svc.Users.Messages.Get("me", id).
+				Context(ctx).
+				Format("metadata").
+				MetadataHeaders("To", "Subject").
+				Do()
+
+			if err != nil {
+				return fmt.Errorf("error while fetching metadata for sent message (message id = %s): %v", id, err)
+			}
+
+			msg := &GmailMessage{
+				Id:       res.Id,
+				ThreadId: res.ThreadId,
+				LabelIds: res.LabelIds,
+				Date:     gmailInternalDate(res.InternalDate),
+			}
+
+			for _, h := range res.Payload.Headers {
+				switch h.Name {
+				case "To":
+					msg.To = h.Value
+				case "Subject":
+					msg.Subject = h.Value
+				}
+			}
+
+			msgs[i] = msg
+
+			return nil
+		})
+	}
+
+	err := group.Wait()
+
+	msgs = slices.DeleteFunc(msgs, func(msg *GmailMessage) bool {
+		return msg == nil
+	})
+
+	return msgs, err
+}
+
+func (t *GmailFollowUpTracker) checkDue(ctx context.Context) {
+	t.mu.Lock()
+
+	now := time.Now()
+	due := make([]*GmailFollowUp, 0)
+	for threadId, f := range t.followUps {
+		if !now.Before(f.DueAt) {
+			due = append(due, f)
+			delete(t.followUps, threadId)
+		}
+	}
+
+	if len(due) > 0 {
+		if err := t.save(); err != nil {
+			slog.Error("error while saving follow ups", "error", err)
+		}
+	}
+
+	t.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	slog.Info("follow ups are due", "numFollowUps", len(due))
+
+	select {
+	case t.remindersChan <- due:
+	case <-ctx.Done():
+	}
+}
+
+// save must be called with t.mu held
+func (t *GmailFollowUpTracker) save() error {
+	return writeJsonFile(followUpsFilePath, t.followUps)
+}
@@ -0,0 +1,141 @@
+package gworkspace_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"google.golang.org/api/gmail/v1"
+)
+
+func newTestGmailService(t *testing.T, msgs map[string]*gmail.Message) *gmail.Service {
+	opts := gworkspace.FakeApi(t, func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		msg, ok := msgs[id]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		json.NewEncoder(w).Encode(msg)
+	})
+
+	svc, err := gmail.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatalf("failed to create gmail service: %v", err)
+	}
+
+	return svc
+}
+
+func sentMessage(id, threadId, subject string) *gmail.Message {
+	return &gmail.Message{
+		Id:       id,
+		ThreadId: threadId,
+		LabelIds: []string{gworkspace.GmailLabel_Sent},
+		Payload: &gmail.MessagePart{Headers: []*gmail.MessagePartHeader{
+			{Name: "To", Value: "bob@example.com"},
+			{Name: "Subject", Value: subject},
+		}},
+	}
+}
+
+func messageAdded(id, threadId string, labels ...string) *gmail.History {
+	return &gmail.History{MessagesAdded: []*gmail.HistoryMessageAdded{
+		{Message: &gmail.Message{Id: id, ThreadId: threadId, LabelIds: labels}},
+	}}
+}
+
+func TestFollowUpTrackerRemindsWithoutReply(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	svc := newTestGmailService(t, map[string]*gmail.Message{
+		"m1": sentMessage("m1", "t1", "Contract [followup]"),
+		"m2": sentMessage("m2", "t2", "Lunch"),
+		"m3": sentMessage("m3", "t3", "Budget [followup]"),
+	})
+
+	tracker, err := gworkspace.NewGmailFollowUpTracker(svc, gworkspace.GmailFollowUpTrackerCfg{Keyword: "[FollowUp]"})
+	if err != nil {
+		t.Fatalf("NewGmailFollowUpTracker returned error: %v", err)
+	}
+
+	err = tracker.HandleGmailHistory(t.Context(), []*gmail.History{
+		messageAdded("m1", "t1", gworkspace.GmailLabel_Sent),
+		messageAdded("m2", "t2", gworkspace.GmailLabel_Sent),
+		messageAdded("m3", "t3", gworkspace.GmailLabel_Sent),
+		messageAdded("r1", "t3", gworkspace.GmailLabel_Inbox, gworkspace.GmailLabel_Unread),
+	})
+	if err != nil {
+		t.Fatalf("HandleGmailHistory returned error: %v", err)
+	}
+
+	// reload from disk to check that pending follow ups are persisted
+	tracker, err = gworkspace.NewGmailFollowUpTracker(svc, gworkspace.GmailFollowUpTrackerCfg{})
+	if err != nil {
+		t.Fatalf("NewGmailFollowUpTracker returned error: %v", err)
+	}
+
+	tracker.CheckDue(t.Context())
+
+	select {
+	case due := <-tracker.Reminders():
+		if len(due) != 1 || due[0].ThreadId != "t1" {
+			t.Fatalf("expected a reminder for thread t1 only, got %+v", due)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected a reminder for thread t1")
+	}
+
+	if len(tracker.FollowUps()) != 0 {
+		t.Errorf("expected no pending follow ups after reminding, got %+v", tracker.FollowUps())
+	}
+}
+
+func TestFollowUpTrackerWaitsFromWhenMessageWasSent(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	now := time.Now().Truncate(time.Millisecond)
+
+	old := sentMessage("m1", "t1", "Contract")
+	old.InternalDate = now.Add(-time.Hour * 72).UnixMilli()
+	recent := sentMessage("m2", "t2", "Lunch")
+	recent.InternalDate = now.Add(-time.Hour).UnixMilli()
+
+	svc := newTestGmailService(t, map[string]*gmail.Message{"m1": old, "m2": recent})
+
+	tracker, err := gworkspace.NewGmailFollowUpTracker(svc, gworkspace.GmailFollowUpTrackerCfg{After: time.Hour * 48})
+	if err != nil {
+		t.Fatalf("NewGmailFollowUpTracker returned error: %v", err)
+	}
+
+	// both are only seen now, e.g. after being offline for days
+	err = tracker.HandleGmailHistory(t.Context(), []*gmail.History{
+		messageAdded("m1", "t1", gworkspace.GmailLabel_Sent),
+		messageAdded("m2", "t2", gworkspace.GmailLabel_Sent),
+	})
+	if err != nil {
+		t.Fatalf("HandleGmailHistory returned error: %v", err)
+	}
+
+	for _, f := range tracker.FollowUps() {
+		if f.ThreadId == "t2" && !f.DueAt.Equal(now.Add(time.Hour*47)) {
+			t.Errorf("expected t2 to be due 48 hours after it was sent, got %v", f.DueAt)
+		}
+	}
+
+	tracker.CheckDue(t.Context())
+
+	select {
+	case due := <-tracker.Reminders():
+		if len(due) != 1 || due[0].ThreadId != "t1" {
+			t.Fatalf("expected a reminder for thread t1 only, got %+v", due)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected a reminder for thread t1")
+	}
+}
@@ -0,0 +1,168 @@
+package gworkspace
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+// GmailReply is a plain text reply to a message, threaded with the original
+// through the In-Reply-To and References headers
+type GmailReply struct {
+	ThreadId string
+
+	From       string
+	To         string
+	Subject    string
+	InReplyTo  string
+	References string
+
+	Body string
+}
+
+// PrepareReply fetches the headers of msg needed to thread a reply to it and
+// returns a reply with an empty body
+func (a *GmailActions) PrepareReply(ctx context.Context, msg *GmailMessage) (*GmailReply, error) {
+	ctx = withGmailUserAction(ctx)
+
+	profile, err := a.svc.Users.GetProfile("me").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting profile from Gmail: %v", err)
+	}
+
+	res, err := a.svc.Users.Messages.Get("me", msg.Id).
+		Context(ctx).
+		Format("metadata").
+		MetadataHeaders("Message-ID", "References", "In-Reply-To", "Subject", "From", "Reply-To").
+		Do()
+
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching headers for message (message id = %s): %v", msg.Id, err)
+	}
+
+	headers := make(map[string]string, len(res.Payload.Headers))
+	for _, h := range res.Payload.Headers {
+		// header names are case insensitive and some senders use Message-Id
+		headers[strings.ToLower(h.Name)] = h.Value
+	}
+
+	return newGmailReply(res.ThreadId, profile.EmailAddress, headers)
+}
+
+// SendReply sends the reply in the thread of the original message. requires
+// the gmail.send scope
+func (a *GmailActions) SendReply(ctx context.Context, r *GmailReply) error {
+	ctx = withGmailUserAction(ctx)
+
+	raw, err := r.Raw()
+	if err != nil {
+		return fmt.Errorf("error while building reply: %v", err)
+	}
+
+	slog.Debug("sending reply", "threadId", r.ThreadId, "to", r.To, "subject", r.Subject)
+
+	_, err = a.svc.Users.Messages.Send("me", &gmail.Message{
+		Raw:      base64.URLEncoding.EncodeToString(raw),
+		ThreadId: r.ThreadId,
+	}).Context(ctx).Do()
+
+	if err != nil {
+		return fmt.Errorf("error while sending reply (thread id = %s): %v", r.ThreadId, err)
+	}
+
+	return nil
+}
+
+// newGmailReply builds a reply from the lower cased headers of the original
+// message
+func newGmailReply(threadId, from string, headers map[string]string) (*GmailReply, error) {
+	to := headers["reply-to"]
+	if to == "" {
+		to = headers["from"]
+	}
+
+	if to == "" {
+		return nil, fmt.Errorf("original message has no sender to reply to")
+	}
+
+	subject := headers["subject"]
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+
+	msgId := headers["message-id"]
+
+	// RFC 5322 3.6.4: the references of the reply are the references of the
+	// original followed by its message id. fall back to its In-Reply-To if
+	// it has no references
+	references := headers["references"]
+	if references == "" {
+		references = headers["in-reply-to"]
+	}
+
+	if msgId != "" {
+		references = strings.TrimSpace(references + " " + msgId)
+	}
+
+	return &GmailReply{
+		ThreadId:   threadId,
+		From:       from,
+		To:         to,
+		Subject:    subject,
+		InReplyTo:  msgId,
+		References: references,
+	}, nil
+}
+
+// Raw returns the reply as an RFC 5322 message
+func (r *GmailReply) Raw() ([]byte, error) {
+	to, err := mail.ParseAddressList(r.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %v", r.To, err)
+	}
+
+	recipients := make([]string, len(to))
+	for i, addr := range to {
+		recipients[i] = addr.String()
+	}
+
+	buf := &bytes.Buffer{}
+
+	writeHeader := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", name, value)
+		}
+	}
+
+	writeHeader("From", r.From)
+	writeHeader("To", strings.Join(recipients, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", r.Subject))
+	writeHeader("In-Reply-To", r.InReplyTo)
+	writeHeader("References", r.References)
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "text/plain; charset=utf-8")
+	writeHeader("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	body := strings.ReplaceAll(r.Body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\n", "\r\n")
+
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode body: %v", err)
+	}
+
+	return buf.Bytes(), nil
+}
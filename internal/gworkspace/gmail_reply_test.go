@@ -0,0 +1,96 @@
+package gworkspace
+
+import (
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestNewGmailReplyThreadsWithOriginal(t *testing.T) {
+	r, err := newGmailReply("t1", "me@example.com", map[string]string{
+		"from":       "Alice <alice@example.com>",
+		"subject":    "Lunch?",
+		"message-id": "<c@example.com>",
+		"references": "<a@example.com> <b@example.com>",
+	})
+	if err != nil {
+		t.Fatalf("newGmailReply returned error: %v", err)
+	}
+
+	if r.To != "Alice <alice@example.com>" {
+		t.Errorf("expected reply to the sender, got %q", r.To)
+	}
+
+	if r.Subject != "Re: Lunch?" {
+		t.Errorf("expected subject %q, got %q", "Re: Lunch?", r.Subject)
+	}
+
+	if r.InReplyTo != "<c@example.com>" {
+		t.Errorf("expected In-Reply-To %q, got %q", "<c@example.com>", r.InReplyTo)
+	}
+
+	if r.References != "<a@example.com> <b@example.com> <c@example.com>" {
+		t.Errorf("expected original message id appended to references, got %q", r.References)
+	}
+}
+
+func TestNewGmailReplyPrefersReplyTo(t *testing.T) {
+	r, err := newGmailReply("t1", "me@example.com", map[string]string{
+		"from":        "Alice <alice@example.com>",
+		"reply-to":    "list@example.com",
+		"subject":     "RE: Lunch?",
+		"message-id":  "<c@example.com>",
+		"in-reply-to": "<b@example.com>",
+	})
+	if err != nil {
+		t.Fatalf("newGmailReply returned error: %v", err)
+	}
+
+	if r.To != "list@example.com" {
+		t.Errorf("expected reply to Reply-To address, got %q", r.To)
+	}
+
+	if r.Subject != "RE: Lunch?" {
+		t.Errorf("expected existing reply prefix to be kept, got %q", r.Subject)
+	}
+
+	if r.References != "<b@example.com> <c@example.com>" {
+		t.Errorf("expected references to fall back to In-Reply-To, got %q", r.References)
+	}
+}
+
+func TestGmailReplyRaw(t *testing.T) {
+	r := &GmailReply{
+		From:       "me@example.com",
+		To:         "Zoë <zoe@example.com>",
+		Subject:    "Re: Café",
+		InReplyTo:  "<c@example.com>",
+		References: "<c@example.com>",
+		Body:       "Sounds good\nSee you there",
+	}
+
+	raw, err := r.Raw()
+	if err != nil {
+		t.Fatalf("Raw returned error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("reply is not a valid message: %v", err)
+	}
+
+	to, err := msg.Header.AddressList("To")
+	if err != nil || len(to) != 1 || to[0].Name != "Zoë" || to[0].Address != "zoe@example.com" {
+		t.Errorf("unexpected To header %q (error = %v)", msg.Header.Get("To"), err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Re: Café" {
+		t.Errorf("unexpected Subject header %q (error = %v)", msg.Header.Get("Subject"), err)
+	}
+
+	if msg.Header.Get("In-Reply-To") != "<c@example.com>" {
+		t.Errorf("unexpected In-Reply-To header %q", msg.Header.Get("In-Reply-To"))
+	}
+}
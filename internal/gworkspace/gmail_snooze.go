@@ -0,0 +1,204 @@
+package gworkspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const snoozedFilePath = "snoozed.json"
+
+// how often snoozed messages are checked. wake times are compared against the
+// wall clock so a message that woke while suspended is shown on the first
+// check after resuming
+const snoozeCheckFreq = time.Second * 30
+
+// hour of the day that "tomorrow morning" snoozes wake at
+const snoozeMorningHour = 8
+
+type GmailSnoozeOption string
+
+const (
+	GmailSnoozeOption_OneHour         GmailSnoozeOption = "1h"
+	GmailSnoozeOption_TwoHours        GmailSnoozeOption = "2h"
+	GmailSnoozeOption_TomorrowMorning GmailSnoozeOption = "tomorrow"
+)
+
+var AllGmailSnoozeOptions = []GmailSnoozeOption{
+	GmailSnoozeOption_OneHour,
+	GmailSnoozeOption_TwoHours,
+	GmailSnoozeOption_TomorrowMorning,
+}
+
+func ParseGmailSnoozeOption(s string) (GmailSnoozeOption, error) {
+	o := GmailSnoozeOption(s)
+	if !slices.Contains(AllGmailSnoozeOptions, o) {
+		return "", fmt.Errorf("unknown snooze option %q", s)
+	}
+
+	return o, nil
+}
+
+// Title is the text shown to the user for the option
+func (o GmailSnoozeOption) Title() string {
+	switch o {
+	case GmailSnoozeOption_OneHour:
+		return "Snooze for 1 hour"
+	case GmailSnoozeOption_TwoHours:
+		return "Snooze for 2 hours"
+	case GmailSnoozeOption_TomorrowMorning:
+		return "Snooze until tomorrow morning"
+	}
+
+	return string(o)
+}
+
+// WakeAt returns when a message snoozed at now should be shown again
+func (o GmailSnoozeOption) WakeAt(now time.Time) time.Time {
+	switch o {
+	case GmailSnoozeOption_OneHour:
+		return now.Add(time.Hour)
+	case GmailSnoozeOption_TwoHours:
+		return now.Add(time.Hour * 2)
+	case GmailSnoozeOption_TomorrowMorning:
+		y, m, d := now.Date()
+		return time.Date(y, m, d+1, snoozeMorningHour, 0, 0, 0, now.Location())
+	}
+
+	panic(fmt.Sprintf("unknown snooze option %q", o))
+}
+
+// GmailSnoozed is a message notification that is hidden until WakeAt
+type GmailSnoozed struct {
+	Message *GmailMessage
+
+	Title string
+	Body  string
+
+	WakeAt time.Time
+}
+
+// GmailSnoozer holds message notifications back until their wake time. nothing
+// is changed in gmail. snoozed messages are persisted so they survive restarts
+type GmailSnoozer struct {
+	mu sync.Mutex
+
+	snoozed map[string]*GmailSnoozed
+
+	wakeChan chan []*GmailSnoozed
+}
+
+func NewGmailSnoozer() (*GmailSnoozer, error) {
+	snoozed := make(map[string]*GmailSnoozed)
+	_, err := readJsonFile(snoozedFilePath, &snoozed)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading snoozed messages: %v", err)
+	}
+
+	return &GmailSnoozer{
+		snoozed:  snoozed,
+		wakeChan: make(chan []*GmailSnoozed, 32),
+	}, nil
+}
+
+// Wake receives snoozed messages once their wake time has passed
+func (s *GmailSnoozer) Wake() <-chan []*GmailSnoozed {
+	return s.wakeChan
+}
+
+// Snooze hides the notification for msg until wakeAt. snoozing a message again
+// replaces its wake time
+func (s *GmailSnoozer) Snooze(msg *GmailMessage, title, body string, wakeAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slog.Debug("snoozing message", "messageId", msg.Id, "wakeAt", wakeAt)
+
+	// strip the monotonic reading so that wakeAt is compared against the wall
+	// clock, which keeps running while suspended
+	s.snoozed[msg.Id] = &GmailSnoozed{
+		Message: msg,
+		Title:   title,
+		Body:    body,
+		WakeAt:  wakeAt.Round(0),
+	}
+
+	err := s.save()
+	if err != nil {
+		return fmt.Errorf("error while saving snoozed messages: %v", err)
+	}
+
+	return nil
+}
+
+// Snoozed returns the snoozed messages, soonest first
+func (s *GmailSnoozer) Snoozed() []GmailSnoozed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snoozed := make([]GmailSnoozed, 0, len(s.snoozed))
+	for _, z := range s.snoozed {
+		snoozed = append(snoozed, *z)
+	}
+
+	slices.SortFunc(snoozed, func(a, b GmailSnoozed) int {
+		return a.WakeAt.Compare(b.WakeAt)
+	})
+
+	return snoozed
+}
+
+func (s *GmailSnoozer) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(snoozeCheckFreq)
+	defer ticker.Stop()
+
+	for {
+		s.checkDue(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *GmailSnoozer) checkDue(ctx context.Context) {
+	s.mu.Lock()
+
+	now := time.Now()
+	due := make([]*GmailSnoozed, 0)
+	for id, z := range s.snoozed {
+		if !now.Before(z.WakeAt) {
+			due = append(due, z)
+			delete(s.snoozed, id)
+		}
+	}
+
+	if len(due) > 0 {
+		if err := s.save(); err != nil {
+			slog.Error("error while saving snoozed messages", "error", err)
+		}
+	}
+
+	s.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	slog.Info("snoozed messages woke up", "numMessages", len(due))
+
+	select {
+	case s.wakeChan <- due:
+	case <-ctx.Done():
+	}
+}
+
+// save must be called with s.mu held
+func (s *GmailSnoozer) save() error {
+	return writeJsonFile(snoozedFilePath, s.snoozed)
+}
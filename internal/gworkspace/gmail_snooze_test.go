@@ -0,0 +1,66 @@
+package gworkspace_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+)
+
+func TestGmailSnoozeOptionTomorrowMorning(t *testing.T) {
+	now := time.Date(2024, time.December, 31, 23, 30, 0, 0, time.UTC)
+
+	expected := time.Date(2025, time.January, 1, 8, 0, 0, 0, time.UTC)
+	if actual := gworkspace.GmailSnoozeOption_TomorrowMorning.WakeAt(now); !actual.Equal(expected) {
+		t.Errorf("expected to wake at %s, got %s", expected, actual)
+	}
+}
+
+func TestGmailSnoozerSurvivesRestart(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	s, err := gworkspace.NewGmailSnoozer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &gworkspace.GmailMessage{Id: "msg", Subject: "Hello"}
+	err = s.Snooze(msg, "New message", "Hello", time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Snooze(&gworkspace.GmailMessage{Id: "later"}, "Later", "", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new snoozer reads the messages snoozed by the previous one
+	s, err = gworkspace.NewGmailSnoozer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(s.Snoozed()); n != 2 {
+		t.Fatalf("expected 2 snoozed messages after restart, got %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	go s.Watch(ctx)
+
+	select {
+	case woken := <-s.Wake():
+		if len(woken) != 1 || woken[0].Message.Id != "msg" || woken[0].Title != "New message" {
+			t.Errorf("expected only the due message to wake, got %+v", woken)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for snoozed message to wake")
+	}
+
+	snoozed := s.Snoozed()
+	if len(snoozed) != 1 || snoozed[0].Message.Id != "later" {
+		t.Errorf("expected only the later message to still be snoozed, got %+v", snoozed)
+	}
+}
@@ -0,0 +1,134 @@
+package gworkspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/gmail/v1"
+)
+
+type GmailLabelCounts struct {
+	LabelId string
+	Name    string
+
+	MessagesUnread int64
+
+	// ThreadsUnread is only updated when the counts are refreshed from gmail
+	// since the history does not say whether a thread was already unread
+	ThreadsUnread int64
+}
+
+// refreshUnreadCounts fetches the unread counts of all watched labels. must be
+// called with g.mu held
+func (g *GmailMonitor) refreshUnreadCounts(ctx context.Context) error {
+	slog.Debug("refreshing unread counts", "labels", g.cfg.Labels)
+
+	group, ctx := errgroup.WithContext(ctx)
+	counts := make([]*GmailLabelCounts, len(g.cfg.Labels))
+
+	for i, id := range g.cfg.Labels {
+		group.Go(func() error {
+			res, err := g.svc.Users.Labels.Get("me", id).Context(ctx).Do()
+			if err != nil {
+				return fmt.Errorf("error while fetching label (label id = %s): %w", id, err)
+			}
+
+			counts[i] = &GmailLabelCounts{
+				LabelId:        res.Id,
+				Name:           res.Name,
+				MessagesUnread: res.MessagesUnread,
+				ThreadsUnread:  res.ThreadsUnread,
+			}
+
+			return nil
+		})
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return err
+	}
+
+	for i, id := range g.cfg.Labels {
+		g.unreadCounts[id] = counts[i]
+	}
+
+	g.unreadRefreshedAt = time.Now()
+
+	return nil
+}
+
+// applyUnreadHistory updates the unread message counts for a history record.
+// must be called with g.mu held
+func (g *GmailMonitor) applyUnreadHistory(h *gmail.History) {
+	// the labels of the message in a history record are the labels after the
+	// change was made
+	adjust := func(labelIds []string, delta int64) {
+		for _, id := range labelIds {
+			c, ok := g.unreadCounts[id]
+			if !ok {
+				continue
+			}
+
+			c.MessagesUnread = max(c.MessagesUnread+delta, 0)
+		}
+	}
+
+	for _, m := range h.MessagesAdded {
+		if slices.Contains(m.Message.LabelIds, GmailLabel_Unread) {
+			adjust(m.Message.LabelIds, 1)
+		}
+	}
+
+	for _, m := range h.MessagesDeleted {
+		if slices.Contains(m.Message.LabelIds, GmailLabel_Unread) {
+			adjust(m.Message.LabelIds, -1)
+		}
+	}
+
+	for _, m := range h.LabelsAdded {
+		if slices.Contains(m.LabelIds, GmailLabel_Unread) {
+			// became unread in every label it has
+			adjust(m.Message.LabelIds, 1)
+		} else if slices.Contains(m.Message.LabelIds, GmailLabel_Unread) {
+			// an unread message was added to new labels
+			adjust(m.LabelIds, 1)
+		}
+	}
+
+	for _, m := range h.LabelsRemoved {
+		if slices.Contains(m.LabelIds, GmailLabel_Unread) {
+			// was read in every label it had, including any removed in the
+			// same change
+			adjust(slices.Concat(m.Message.LabelIds, m.LabelIds), -1)
+		} else if slices.Contains(m.Message.LabelIds, GmailLabel_Unread) {
+			// an unread message was removed from labels
+			adjust(m.LabelIds, -1)
+		}
+	}
+}
+
+// publishUnreadCounts replaces any counts that have not been received yet with
+// the current counts. must be called with g.mu held
+func (g *GmailMonitor) publishUnreadCounts(ctx context.Context) {
+	counts := make([]GmailLabelCounts, 0, len(g.cfg.Labels))
+	for _, id := range g.cfg.Labels {
+		if c, ok := g.unreadCounts[id]; ok {
+			counts = append(counts, *c)
+		}
+	}
+
+	select {
+	case <-g.unreadCountsChan:
+	default:
+	}
+
+	select {
+	case g.unreadCountsChan <- counts:
+	case <-ctx.Done():
+	}
+}
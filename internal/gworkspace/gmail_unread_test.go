@@ -0,0 +1,136 @@
+package gworkspace
+
+import (
+	"maps"
+	"testing"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+func TestApplyUnreadHistory(t *testing.T) {
+	const (
+		inbox = "INBOX"
+		work  = "Label_work"
+		other = "Label_other"
+	)
+
+	msg := func(labelIds ...string) *gmail.Message {
+		return &gmail.Message{Id: "1", LabelIds: labelIds}
+	}
+
+	tests := []struct {
+		name     string
+		counts   map[string]int64
+		history  *gmail.History
+		expected map[string]int64
+	}{
+		{
+			name:     "new unread message",
+			counts:   map[string]int64{inbox: 2, work: 0},
+			history:  &gmail.History{MessagesAdded: []*gmail.HistoryMessageAdded{{Message: msg(inbox, GmailLabel_Unread)}}},
+			expected: map[string]int64{inbox: 3, work: 0},
+		},
+		{
+			name:     "new read message",
+			counts:   map[string]int64{inbox: 2},
+			history:  &gmail.History{MessagesAdded: []*gmail.HistoryMessageAdded{{Message: msg(inbox)}}},
+			expected: map[string]int64{inbox: 2},
+		},
+		{
+			name:     "marked unread on a watched label",
+			counts:   map[string]int64{inbox: 2, work: 1},
+			history:  &gmail.History{LabelsAdded: []*gmail.HistoryLabelAdded{{LabelIds: []string{GmailLabel_Unread}, Message: msg(inbox, work, GmailLabel_Unread)}}},
+			expected: map[string]int64{inbox: 3, work: 2},
+		},
+		{
+			name:     "marked read on a watched label",
+			counts:   map[string]int64{inbox: 2, work: 1},
+			history:  &gmail.History{LabelsRemoved: []*gmail.HistoryLabelRemoved{{LabelIds: []string{GmailLabel_Unread}, Message: msg(inbox, work)}}},
+			expected: map[string]int64{inbox: 1, work: 0},
+		},
+		{
+			name:     "marked read and archived in one change",
+			counts:   map[string]int64{inbox: 2, work: 1},
+			history:  &gmail.History{LabelsRemoved: []*gmail.HistoryLabelRemoved{{LabelIds: []string{GmailLabel_Unread, inbox}, Message: msg(work)}}},
+			expected: map[string]int64{inbox: 1, work: 0},
+		},
+		{
+			name:     "marked read on an unwatched label",
+			counts:   map[string]int64{inbox: 2},
+			history:  &gmail.History{LabelsRemoved: []*gmail.HistoryLabelRemoved{{LabelIds: []string{GmailLabel_Unread}, Message: msg(other)}}},
+			expected: map[string]int64{inbox: 2},
+		},
+		{
+			name:     "label added to an unread message",
+			counts:   map[string]int64{inbox: 2, work: 0},
+			history:  &gmail.History{LabelsAdded: []*gmail.HistoryLabelAdded{{LabelIds: []string{work}, Message: msg(inbox, work, GmailLabel_Unread)}}},
+			expected: map[string]int64{inbox: 2, work: 1},
+		},
+		{
+			name:     "label added to a read message",
+			counts:   map[string]int64{inbox: 2, work: 0},
+			history:  &gmail.History{LabelsAdded: []*gmail.HistoryLabelAdded{{LabelIds: []string{work}, Message: msg(inbox, work)}}},
+			expected: map[string]int64{inbox: 2, work: 0},
+		},
+		{
+			name:     "label removed from an unread message",
+			counts:   map[string]int64{inbox: 2, work: 1},
+			history:  &gmail.History{LabelsRemoved: []*gmail.HistoryLabelRemoved{{LabelIds: []string{inbox}, Message: msg(work, GmailLabel_Unread)}}},
+			expected: map[string]int64{inbox: 1, work: 1},
+		},
+		{
+			name:     "label removed from a read message",
+			counts:   map[string]int64{inbox: 2},
+			history:  &gmail.History{LabelsRemoved: []*gmail.HistoryLabelRemoved{{LabelIds: []string{inbox}, Message: msg(work)}}},
+			expected: map[string]int64{inbox: 2},
+		},
+		{
+			name:     "unread message deleted",
+			counts:   map[string]int64{inbox: 2, work: 1},
+			history:  &gmail.History{MessagesDeleted: []*gmail.HistoryMessageDeleted{{Message: msg(inbox, GmailLabel_Unread)}}},
+			expected: map[string]int64{inbox: 1, work: 1},
+		},
+		{
+			name:     "read message deleted",
+			counts:   map[string]int64{inbox: 2},
+			history:  &gmail.History{MessagesDeleted: []*gmail.HistoryMessageDeleted{{Message: msg(inbox)}}},
+			expected: map[string]int64{inbox: 2},
+		},
+		{
+			name:   "stale counts do not go negative",
+			counts: map[string]int64{inbox: 0, work: 0},
+			history: &gmail.History{
+				MessagesDeleted: []*gmail.HistoryMessageDeleted{{Message: msg(inbox, GmailLabel_Unread)}},
+				LabelsRemoved: []*gmail.HistoryLabelRemoved{
+					{LabelIds: []string{GmailLabel_Unread}, Message: msg(inbox, work)},
+					{LabelIds: []string{work}, Message: msg(GmailLabel_Unread)},
+				},
+			},
+			expected: map[string]int64{inbox: 0, work: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GmailMonitor{unreadCounts: make(map[string]*GmailLabelCounts)}
+			for id, n := range tt.counts {
+				g.unreadCounts[id] = &GmailLabelCounts{LabelId: id, MessagesUnread: n}
+			}
+
+			g.applyUnreadHistory(tt.history)
+
+			got := make(map[string]int64)
+			for id, c := range g.unreadCounts {
+				if c.MessagesUnread < 0 {
+					t.Errorf("expected the count of %s to not be negative, got %d", id, c.MessagesUnread)
+				}
+
+				got[id] = c.MessagesUnread
+			}
+
+			if !maps.Equal(got, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package gworkspace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/people/v1"
+)
+
+const vipPendingFilePath = "vip_pending.json"
+
+// how often pending vip messages are checked for re-notification
+const vipCheckFreq = time.Second * 30
+
+// how often the members of vip contact groups are fetched
+const vipContactGroupRefreshFreq = time.Hour
+
+// maximum number of people that can be fetched in a single batch
+const peopleMaxBatchSize = 200
+
+type GmailVipCfg struct {
+	Addresses []string
+	Domains   []string
+
+	// ContactGroups are the resource names (contactGroups/...) or names of
+	// google contact groups whose members are vips
+	ContactGroups []string
+
+	// RenotifyIntervals are the delays before each re-notification of an
+	// unread vip message. the last interval is repeated
+	RenotifyIntervals []time.Duration
+
+	// MaxRenotify is the maximum number of re-notifications of a message
+	MaxRenotify int
+}
+
+// GmailVipRenotify is a vip message that is still unread and should be shown
+// again
+type GmailVipRenotify struct {
+	Message *GmailMessage
+
+	// Count is the number of times the message has been re-notified,
+	// including this one
+	Count int
+}
+
+type gmailVipPending struct {
+	Message *GmailMessage
+	Count   int
+	NextAt  time.Time
+}
+
+// GmailVips decides which senders are vips and re-notifies unread messages from
+// them until they are read. it is fed history records by a GmailMonitor to
+// learn when messages are read and persists pending re-notifications so they
+// survive restarts
+type GmailVips struct {
+	mu  sync.Mutex
+	svc *people.Service
+	cfg GmailVipCfg
+
+	groupAddresses    []string
+	groupsRefreshedAt time.Time
+
+	pending map[string]*gmailVipPending
+
+	renotifyChan chan []*GmailVipRenotify
+}
+
+var _ GmailHistoryHandler = (*GmailVips)(nil)
+
+// NewGmailVips creates a vip list. svc is only used to resolve contact groups
+// and may be nil if there are none
+func NewGmailVips(svc *people.Service, cfg GmailVipCfg) (*GmailVips, error) {
+	cfg.Addresses = lowerAll(cfg.Addresses)
+	cfg.Domains = lowerAll(cfg.Domains)
+
+	pending := make(map[string]*gmailVipPending)
+	_, err := readJsonFile(vipPendingFilePath, &pending)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading pending vip messages: %v", err)
+	}
+
+	return &GmailVips{
+		svc:          svc,
+		cfg:          cfg,
+		pending:      pending,
+		renotifyChan: make(chan []*GmailVipRenotify, 32),
+	}, nil
+}
+
+// Renotify receives vip messages that are still unread once their next
+// re-notification is due
+func (v *GmailVips) Renotify() <-chan []*GmailVipRenotify {
+	return v.renotifyChan
+}
+
+// IsVip reports whether the sender in a From header is a vip
+func (v *GmailVips) IsVip(from string) bool {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		slog.Debug("failed to parse sender while checking vips", "from", from, "error", err)
+		return false
+	}
+
+	a := strings.ToLower(addr.Address)
+	_, domain, _ := strings.Cut(a, "@")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return slices.Contains(v.cfg.Addresses, a) ||
+		slices.Contains(v.cfg.Domains, domain) ||
+		slices.Contains(v.groupAddresses, a)
+}
+
+// Track starts re-notifying msg until it is read
+func (v *GmailVips) Track(msg *GmailMessage) {
+	if len(v.cfg.RenotifyIntervals) == 0 || v.cfg.MaxRenotify <= 0 {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.pending[msg.Id] = &gmailVipPending{
+		Message: msg,
+		NextAt:  time.Now().Add(v.cfg.RenotifyIntervals[0]).Round(0),
+	}
+
+	if err := v.save(); err != nil {
+		slog.Error("error while saving pending vip messages", "error", err)
+	}
+}
+
+func (v *GmailVips) HandleGmailHistory(ctx context.Context, history []*gmail.History) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	changed := false
+	stop := func(id string, reason string) {
+		if _, ok := v.pending[id]; ok {
+			slog.Debug("no longer re-notifying vip message", "messageId", id, "reason", reason)
+			delete(v.pending, id)
+			changed = true
+		}
+	}
+
+	for _, h := range history {
+		for _, m := range h.LabelsRemoved {
+			if slices.Contains(m.LabelIds, GmailLabel_Unread) {
+				stop(m.Message.Id, "read")
+			}
+		}
+
+		for _, m := range h.LabelsAdded {
+			if slices.Contains(m.LabelIds, GmailLabel_Trash) {
+				stop(m.Message.Id, "trashed")
+			}
+		}
+
+		for _, m := range h.MessagesDeleted {
+			stop(m.Message.Id, "deleted")
+		}
+	}
+
+	if changed {
+		if err := v.save(); err != nil {
+			return fmt.Errorf("error while saving pending vip messages: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (v *GmailVips) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(vipCheckFreq)
+	defer ticker.Stop()
+
+	for {
+		if v.svc != nil && len(v.cfg.ContactGroups) > 0 && time.Since(v.groupsRefreshedAt) >= vipContactGroupRefreshFreq {
+			err := v.refreshContactGroups(ctx)
+			if err != nil {
+				slog.Error("error while refreshing vip contact groups", "error", err)
+			}
+		}
+
+		v.checkDue(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (v *GmailVips) checkDue(ctx context.Context) {
+	v.mu.Lock()
+
+	now := time.Now()
+	due := make([]*GmailVipRenotify, 0)
+	for id, p := range v.pending {
+		if now.Before(p.NextAt) {
+			continue
+		}
+
+		p.Count++
+		due = append(due, &GmailVipRenotify{Message: p.Message, Count: p.Count})
+
+		if p.Count >= v.cfg.MaxRenotify {
+			delete(v.pending, id)
+			continue
+		}
+
+		i := min(p.Count, len(v.cfg.RenotifyIntervals)-1)
+		p.NextAt = now.Add(v.cfg.RenotifyIntervals[i]).Round(0)
+	}
+
+	if len(due) > 0 {
+		if err := v.save(); err != nil {
+			slog.Error("error while saving pending vip messages", "error", err)
+		}
+	}
+
+	v.mu.Unlock()
+
+	if len(due) == 0 {
+		return
+	}
+
+	select {
+	case v.renotifyChan <- due:
+	case <-ctx.Done():
+	}
+}
+
+// save must be called with v.mu held
+func (v *GmailVips) save() error {
+	return writeJsonFile(vipPendingFilePath, v.pending)
+}
+
+func (v *GmailVips) refreshContactGroups(ctx context.Context) error {
+	slog.Debug("refreshing vip contact groups", "groups", v.cfg.ContactGroups)
+
+	// groups can be given by name, so resolve names to resource names first
+	resourceNames := make([]string, 0, len(v.cfg.ContactGroups))
+	names := make([]string, 0)
+	for _, g := range v.cfg.ContactGroups {
+		if strings.HasPrefix(g, "contactGroups/") {
+			resourceNames = append(resourceNames, g)
+		} else {
+			names = append(names, g)
+		}
+	}
+
+	if len(names) > 0 {
+		err := v.svc.ContactGroups.List().PageSize(1000).Pages(ctx, func(res *people.ListContactGroupsResponse) error {
+			for _, g := range res.ContactGroups {
+				if slices.Contains(names, g.Name) || slices.Contains(names, g.FormattedName) {
+					resourceNames = append(resourceNames, g.ResourceName)
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("error while listing contact groups: %v", err)
+		}
+	}
+
+	memberNames := make([]string, 0)
+	for _, name := range resourceNames {
+		g, err := v.svc.ContactGroups.Get(name).MaxMembers(1000).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error while fetching contact group (resource name = %s): %v", name, err)
+		}
+
+		memberNames = append(memberNames, g.MemberResourceNames...)
+	}
+
+	addrs := make([]string, 0, len(memberNames))
+	for batch := range slices.Chunk(memberNames, peopleMaxBatchSize) {
+		res, err := v.svc.People.GetBatchGet().
+			ResourceNames(batch...).
+			PersonFields("emailAddresses").
+			Context(ctx).
+			Do()
+
+		if err != nil {
+			return fmt.Errorf("error while fetching contact group members: %v", err)
+		}
+
+		for _, r := range res.Responses {
+			if r.Person == nil {
+				continue
+			}
+
+			for _, e := range r.Person.EmailAddresses {
+				addrs = append(addrs, strings.ToLower(e.Value))
+			}
+		}
+	}
+
+	slog.Debug("fetched vip contact group members", "numAddresses", len(addrs))
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.groupAddresses = addrs
+	v.groupsRefreshedAt = time.Now()
+
+	return nil
+}
+
+func lowerAll(s []string) []string {
+	lower := make([]string, len(s))
+	for i, v := range s {
+		lower[i] = strings.ToLower(v)
+	}
+
+	return lower
+}
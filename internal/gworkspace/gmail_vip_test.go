@@ -0,0 +1,121 @@
+package gworkspace
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/api/gmail/v1"
+)
+
+func TestGmailVipsIsVip(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	v, err := NewGmailVips(nil, GmailVipCfg{
+		Addresses: []string{"Boss@Example.com"},
+		Domains:   []string{"customer.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.groupAddresses = []string{"team@example.com"}
+
+	tests := []struct {
+		from     string
+		expected bool
+	}{
+		{from: "The Boss <boss@example.com>", expected: true},
+		{from: "someone@customer.com", expected: true},
+		{from: "Team <TEAM@example.com>", expected: true},
+		{from: "someone@notcustomer.com", expected: false},
+		{from: "other@example.com", expected: false},
+		{from: "not an address", expected: false},
+	}
+
+	for _, test := range tests {
+		if actual := v.IsVip(test.from); actual != test.expected {
+			t.Errorf("IsVip(%q) = %v, expected %v", test.from, actual, test.expected)
+		}
+	}
+}
+
+func TestGmailVipsRenotifyUntilRead(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cfg := GmailVipCfg{
+		RenotifyIntervals: []time.Duration{time.Minute, time.Hour},
+		MaxRenotify:       3,
+	}
+
+	v, err := NewGmailVips(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := &GmailMessage{Id: "read"}
+	unread := &GmailMessage{Id: "unread"}
+	v.Track(read)
+	v.Track(unread)
+
+	for _, p := range v.pending {
+		p.NextAt = time.Now().Add(-time.Second)
+	}
+
+	v.checkDue(t.Context())
+
+	due := <-v.Renotify()
+	if len(due) != 2 || due[0].Count != 1 || due[1].Count != 1 {
+		t.Fatalf("expected 2 first re-notifications, got %+v", due)
+	}
+
+	// a restarted vip list carries on where it left off
+	v, err = NewGmailVips(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.pending) != 2 || v.pending["unread"].Count != 1 {
+		t.Fatalf("expected pending messages to be restored, got %+v", v.pending)
+	}
+
+	// the second interval is used after the first re-notification
+	if next := time.Until(v.pending["unread"].NextAt); next < time.Minute*59 {
+		t.Errorf("expected next re-notification in about an hour, got %s", next)
+	}
+
+	err = v.HandleGmailHistory(t.Context(), []*gmail.History{{
+		LabelsRemoved: []*gmail.HistoryLabelRemoved{{
+			Message:  &gmail.Message{Id: "read"},
+			LabelIds: []string{GmailLabel_Unread},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := v.pending["read"]; ok {
+		t.Errorf("expected read message to no longer be tracked")
+	}
+
+	for i := range 2 {
+		v.pending["unread"].NextAt = time.Now().Add(-time.Second)
+		v.checkDue(t.Context())
+
+		due := <-v.Renotify()
+		if len(due) != 1 || due[0].Count != i+2 {
+			t.Fatalf("expected re-notification %d, got %+v", i+2, due)
+		}
+	}
+
+	if _, ok := v.pending["unread"]; ok {
+		t.Errorf("expected message to no longer be tracked after %d re-notifications", v.cfg.MaxRenotify)
+	}
+
+	v, err = NewGmailVips(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(v.pending) != 0 {
+		t.Errorf("expected no pending messages after a restart, got %d", len(v.pending))
+	}
+}
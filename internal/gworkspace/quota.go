@@ -0,0 +1,402 @@
+package gworkspace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gmailQuotaMethod is the quota cost of a gmail api method. see
+// https://developers.google.com/workspace/gmail/api/reference/quota
+type gmailQuotaMethod struct {
+	httpMethod string
+
+	// pattern is matched against the path after /gmail/v1/users/{userId}/.
+	// * matches any single segment
+	pattern string
+
+	name  string
+	units int64
+}
+
+var gmailQuotaMethods = []gmailQuotaMethod{
+	{httpMethod: http.MethodGet, pattern: "profile", name: "getProfile", units: 1},
+	{httpMethod: http.MethodGet, pattern: "history", name: "history.list", units: 2},
+	{httpMethod: http.MethodGet, pattern: "labels", name: "labels.list", units: 1},
+	{httpMethod: http.MethodGet, pattern: "labels/*", name: "labels.get", units: 1},
+	{httpMethod: http.MethodGet, pattern: "messages", name: "messages.list", units: 5},
+	{httpMethod: http.MethodGet, pattern: "messages/*", name: "messages.get", units: 5},
+	{httpMethod: http.MethodGet, pattern: "messages/*/attachments/*", name: "messages.attachments.get", units: 5},
+	{httpMethod: http.MethodPost, pattern: "messages/send", name: "messages.send", units: 100},
+	{httpMethod: http.MethodPost, pattern: "messages/batchModify", name: "messages.batchModify", units: 50},
+	{httpMethod: http.MethodPost, pattern: "messages/*/modify", name: "messages.modify", units: 5},
+	{httpMethod: http.MethodPost, pattern: "messages/*/trash", name: "messages.trash", units: 5},
+	{httpMethod: http.MethodPost, pattern: "messages/*/untrash", name: "messages.untrash", units: 5},
+	{httpMethod: http.MethodGet, pattern: "threads", name: "threads.list", units: 10},
+	{httpMethod: http.MethodGet, pattern: "threads/*", name: "threads.get", units: 10},
+	{httpMethod: http.MethodPost, pattern: "threads/*/modify", name: "threads.modify", units: 10},
+	{httpMethod: http.MethodPost, pattern: "threads/*/trash", name: "threads.trash", units: 10},
+	{httpMethod: http.MethodPost, pattern: "threads/*/untrash", name: "threads.untrash", units: 10},
+	{httpMethod: http.MethodGet, pattern: "settings/sendAs", name: "settings.sendAs.list", units: 1},
+}
+
+// cost of gmail methods that are not in gmailQuotaMethods. most methods cost 5
+// units, so this is a reasonable guess
+const gmailQuotaUnknownMethodUnits = 5
+
+// cost of a single GmailMonitor check when there are no new messages
+var gmailCheckQuotaUnits = gmailQuotaUnits("history.list")
+
+// how long gmail requests are held back after gmail reports that the rate
+// limit was exceeded. the backoff doubles with every rate limited response
+// until a request succeeds
+const (
+	gmailQuotaMinBackoff = time.Second * 30
+	gmailQuotaMaxBackoff = time.Minute * 15
+)
+
+// ErrGmailQuotaExceeded is returned for polling requests that were not sent
+// because they would exceed the budget, or because gmail recently reported
+// that the rate limit was exceeded
+var ErrGmailQuotaExceeded = errors.New("gmail quota budget exceeded")
+
+type GmailQuotaWindow struct {
+	Duration time.Duration
+	Budget   int64
+}
+
+type GmailQuotaWindowUsage struct {
+	GmailQuotaWindow
+	Used int64
+}
+
+type GmailQuotaMethodUsage struct {
+	Method string
+
+	// Calls and Units are totals since the quota was created
+	Calls int64
+	Units int64
+}
+
+type GmailQuotaUsage struct {
+	Windows []GmailQuotaWindowUsage
+	Methods []GmailQuotaMethodUsage
+
+	// BackoffUntil is when gmail requests are sent again after gmail reported
+	// that the rate limit was exceeded. zero if not backing off
+	BackoffUntil time.Time
+}
+
+type gmailQuotaEvent struct {
+	at    time.Time
+	units int64
+}
+
+// GmailQuota counts the gmail quota units used by requests made through its
+// transport over rolling windows. polling requests that would exceed the
+// budget of a window are not sent, and neither are they while backing off
+// after gmail rate limited a request. requests made on behalf of the user are
+// counted but always sent
+type GmailQuota struct {
+	mu sync.Mutex
+
+	windows []GmailQuotaWindow
+	events  []gmailQuotaEvent
+	methods map[string]*GmailQuotaMethodUsage
+
+	backoff      time.Duration
+	backoffUntil time.Time
+}
+
+func NewGmailQuota(windows []GmailQuotaWindow) *GmailQuota {
+	return &GmailQuota{
+		windows: windows,
+		methods: make(map[string]*GmailQuotaMethodUsage),
+	}
+}
+
+// Transport wraps base so that every gmail request made through it is counted
+// against the budget. requests to other apis are passed through untouched
+func (q *GmailQuota) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &gmailQuotaTransport{q: q, base: base}
+}
+
+// WouldExceed reports whether using units more would exceed the budget of any
+// window, or requests are held back after being rate limited
+func (q *GmailQuota) WouldExceed(units int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.wouldExceed(time.Now(), units)
+}
+
+// wouldExceed must be called with q.mu held
+func (q *GmailQuota) wouldExceed(now time.Time, units int64) bool {
+	if now.Before(q.backoffUntil) {
+		return true
+	}
+
+	for _, w := range q.windows {
+		if q.used(now, w.Duration)+units > w.Budget {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *GmailQuota) Usage() GmailQuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	usage := GmailQuotaUsage{}
+	for _, w := range q.windows {
+		usage.Windows = append(usage.Windows, GmailQuotaWindowUsage{
+			GmailQuotaWindow: w,
+			Used:             q.used(now, w.Duration),
+		})
+	}
+
+	for _, m := range q.methods {
+		usage.Methods = append(usage.Methods, *m)
+	}
+
+	slices.SortFunc(usage.Methods, func(a, b GmailQuotaMethodUsage) int {
+		return strings.Compare(a.Method, b.Method)
+	})
+
+	if now.Before(q.backoffUntil) {
+		usage.BackoffUntil = q.backoffUntil
+	}
+
+	return usage
+}
+
+// reserve records units used by method, unless they would exceed the budget.
+// user actions are always recorded
+func (q *GmailQuota) reserve(method string, units int64, userAction bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	if userAction {
+		q.record(now, method, units)
+		return nil
+	}
+
+	if now.Before(q.backoffUntil) {
+		return fmt.Errorf("error while calling %s: %w, rate limited until %s", method, ErrGmailQuotaExceeded, q.backoffUntil.Format(time.TimeOnly))
+	}
+
+	if q.wouldExceed(now, units) {
+		return fmt.Errorf("error while calling %s: %w", method, ErrGmailQuotaExceeded)
+	}
+
+	q.record(now, method, units)
+
+	return nil
+}
+
+// rateLimited holds back requests for longer each time gmail rate limits a
+// request in a row. retryAfter is used instead if gmail asked for longer
+func (q *GmailQuota) rateLimited(retryAfter time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.backoff = min(max(q.backoff*2, gmailQuotaMinBackoff), gmailQuotaMaxBackoff)
+	q.backoffUntil = time.Now().Add(max(q.backoff, retryAfter))
+
+	slog.Warn("gmail rate limit exceeded, holding back requests", "until", q.backoffUntil)
+}
+
+func (q *GmailQuota) succeeded() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.backoff = 0
+}
+
+// record must be called with q.mu held
+func (q *GmailQuota) record(now time.Time, method string, units int64) {
+	m, ok := q.methods[method]
+	if !ok {
+		m = &GmailQuotaMethodUsage{Method: method}
+		q.methods[method] = m
+	}
+
+	m.Calls++
+	m.Units += units
+
+	q.events = append(q.events, gmailQuotaEvent{at: now, units: units})
+
+	// events older than the longest window are no longer needed
+	var longest time.Duration
+	for _, w := range q.windows {
+		longest = max(longest, w.Duration)
+	}
+
+	i := slices.IndexFunc(q.events, func(e gmailQuotaEvent) bool { return now.Sub(e.at) < longest })
+	if i < 0 {
+		q.events = q.events[:0]
+	} else {
+		q.events = slices.Delete(q.events, 0, i)
+	}
+}
+
+// used must be called with q.mu held
+func (q *GmailQuota) used(now time.Time, window time.Duration) int64 {
+	var units int64
+	for _, e := range slices.Backward(q.events) {
+		if now.Sub(e.at) >= window {
+			break
+		}
+
+		units += e.units
+	}
+
+	return units
+}
+
+type gmailUserActionKey struct{}
+
+// withGmailUserAction marks the gmail requests made with ctx as done on behalf
+// of the user, so that the quota does not hold them back
+func withGmailUserAction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, gmailUserActionKey{}, true)
+}
+
+func isGmailUserAction(ctx context.Context) bool {
+	userAction, _ := ctx.Value(gmailUserActionKey{}).(bool)
+	return userAction
+}
+
+type gmailQuotaTransport struct {
+	q    *GmailQuota
+	base http.RoundTripper
+}
+
+func (t *gmailQuotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method, ok := gmailQuotaMethodName(req)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	err := t.q.reserve(method, gmailQuotaUnits(method), isGmailUserAction(req.Context()))
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+
+		return nil, err
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if isGmailRateLimited(res) {
+		t.q.rateLimited(retryAfter(res))
+	} else if res.StatusCode < 400 {
+		t.q.succeeded()
+	}
+
+	return res, nil
+}
+
+// isGmailRateLimited reports whether gmail rejected a request because of its
+// rate limits. gmail answers with 429, or with 403 and a rateLimitExceeded or
+// userRateLimitExceeded reason
+func isGmailRateLimited(res *http.Response) bool {
+	if res.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if res.StatusCode != http.StatusForbidden {
+		return false
+	}
+
+	// the body is put back so that the caller still gets the error
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(b))
+
+	if err != nil {
+		return false
+	}
+
+	return bytes.Contains(b, []byte(`"rateLimitExceeded"`)) || bytes.Contains(b, []byte(`"userRateLimitExceeded"`))
+}
+
+// retryAfter returns the delay in the Retry-After header of res, or 0
+func retryAfter(res *http.Response) time.Duration {
+	secs, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+
+	return time.Duration(secs) * time.Second
+}
+
+// gmailQuotaMethodName returns the name of the gmail method called by req, or
+// false if it is not a gmail request
+func gmailQuotaMethodName(req *http.Request) (string, bool) {
+	_, path, ok := strings.Cut(req.URL.Path, "/gmail/v1/users/")
+	if !ok {
+		return "", false
+	}
+
+	// drop the user id
+	_, path, _ = strings.Cut(path, "/")
+	segments := strings.Split(path, "/")
+
+	for _, m := range gmailQuotaMethods {
+		if m.httpMethod == req.Method && matchGmailQuotaPattern(m.pattern, segments) {
+			return m.name, true
+		}
+	}
+
+	slog.Debug("unknown gmail method while counting quota", "method", req.Method, "path", req.URL.Path)
+
+	return "other", true
+}
+
+func matchGmailQuotaPattern(pattern string, segments []string) bool {
+	p := strings.Split(pattern, "/")
+	if len(p) != len(segments) {
+		return false
+	}
+
+	for i := range p {
+		if p[i] != "*" && p[i] != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func gmailQuotaUnits(method string) int64 {
+	for _, m := range gmailQuotaMethods {
+		if m.name == method {
+			return m.units
+		}
+	}
+
+	return gmailQuotaUnknownMethodUnits
+}
@@ -0,0 +1,212 @@
+package gworkspace_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/people/v1"
+)
+
+// newTestQuotaServices points gmail and people at handler, with every request
+// going through the transport of q
+func newTestQuotaServices(t *testing.T, q *gworkspace.GmailQuota, handler http.HandlerFunc) (*gmail.Service, *people.Service) {
+	// the later client replaces the one of the fake api, which is plain http
+	opts := append(gworkspace.FakeApi(t, handler), option.WithHTTPClient(&http.Client{Transport: q.Transport(nil)}))
+
+	gmailSvc, err := gmail.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peopleSvc, err := people.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return gmailSvc, peopleSvc
+}
+
+func TestGmailQuotaCountsUnitsPerMethod(t *testing.T) {
+	q := gworkspace.NewGmailQuota([]gworkspace.GmailQuotaWindow{{Duration: time.Minute, Budget: 22}})
+	gmailSvc, peopleSvc := newTestQuotaServices(t, q, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "{}")
+	})
+
+	calls := []func() error{
+		func() error { _, err := gmailSvc.Users.History.List("me").Do(); return err },
+		func() error { _, err := gmailSvc.Users.Messages.Get("me", "abc").Do(); return err },
+		func() error { _, err := gmailSvc.Users.Messages.Get("me", "def").Do(); return err },
+		func() error {
+			_, err := gmailSvc.Users.Threads.Modify("me", "abc", &gmail.ModifyThreadRequest{}).Do()
+			return err
+		},
+
+		// not gmail, so not counted
+		func() error { _, err := peopleSvc.People.Get("people/me").PersonFields("names").Do(); return err },
+	}
+
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage := q.Usage()
+
+	expectedMethods := []gworkspace.GmailQuotaMethodUsage{
+		{Method: "history.list", Calls: 1, Units: 2},
+		{Method: "messages.get", Calls: 2, Units: 10},
+		{Method: "threads.modify", Calls: 1, Units: 10},
+	}
+
+	if len(usage.Methods) != len(expectedMethods) {
+		t.Fatalf("expected usage for %d methods, got %+v", len(expectedMethods), usage.Methods)
+	}
+
+	for i, expected := range expectedMethods {
+		if usage.Methods[i] != expected {
+			t.Errorf("expected %+v, got %+v", expected, usage.Methods[i])
+		}
+	}
+
+	if len(usage.Windows) != 1 || usage.Windows[0].Used != 22 {
+		t.Errorf("expected 22 units used in the window, got %+v", usage.Windows)
+	}
+
+	if !q.WouldExceed(1) {
+		t.Errorf("expected budget to be exceeded")
+	}
+}
+
+func TestGmailQuotaHoldsBackRequestsOverBudget(t *testing.T) {
+	sent := 0
+
+	q := gworkspace.NewGmailQuota([]gworkspace.GmailQuotaWindow{{Duration: time.Minute, Budget: 12}})
+	gmailSvc, peopleSvc := newTestQuotaServices(t, q, func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		io.WriteString(w, "{}")
+	})
+
+	_, err := gmailSvc.Users.Messages.Get("me", "abc").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an attachment costs 5 more units, the second one would exceed the budget
+	_, err = gmailSvc.Users.Messages.Attachments.Get("me", "abc", "1").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = gmailSvc.Users.Messages.Attachments.Get("me", "abc", "2").Do()
+	if !errors.Is(err, gworkspace.ErrGmailQuotaExceeded) {
+		t.Fatalf("expected the request over budget to fail with %v, got %v", gworkspace.ErrGmailQuotaExceeded, err)
+	}
+
+	// other apis are not limited by the gmail budget
+	_, err = peopleSvc.People.Get("people/me").PersonFields("names").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sent != 3 {
+		t.Errorf("expected 3 requests to be sent, got %d", sent)
+	}
+}
+
+func TestGmailQuotaLetsUserActionsThrough(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	sent := 0
+
+	q := gworkspace.NewGmailQuota([]gworkspace.GmailQuotaWindow{{Duration: time.Minute, Budget: 5}})
+	gmailSvc, _ := newTestQuotaServices(t, q, func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		io.WriteString(w, "{}")
+	})
+
+	_, err := gmailSvc.Users.Messages.Get("me", "abc").Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acts, err := gworkspace.NewGmailActions(gmailSvc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the budget is used up, but archiving is done for the user
+	err = acts.ApplyToMessage(t.Context(), gworkspace.GmailAction_Archive, &gworkspace.GmailMessage{Id: "abc"})
+	if err != nil {
+		t.Fatalf("expected the user action to be sent, got %v", err)
+	}
+
+	if sent != 2 || q.Usage().Windows[0].Used != 10 {
+		t.Errorf("expected the user action to be sent and counted, got %d sent and %+v", sent, q.Usage().Windows)
+	}
+
+	_, err = gmailSvc.Users.Messages.Get("me", "def").Do()
+	if !errors.Is(err, gworkspace.ErrGmailQuotaExceeded) {
+		t.Errorf("expected polling to still be held back, got %v", err)
+	}
+}
+
+func TestGmailQuotaBacksOffWhenRateLimited(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected bool
+	}{
+		{name: "too many requests", status: http.StatusTooManyRequests, expected: true},
+		{name: "rate limit exceeded", status: http.StatusForbidden, body: `{"error":{"code":403,"errors":[{"reason":"rateLimitExceeded"}]}}`, expected: true},
+		{name: "user rate limit exceeded", status: http.StatusForbidden, body: `{"error":{"code":403,"errors":[{"reason":"userRateLimitExceeded"}]}}`, expected: true},
+		{name: "forbidden", status: http.StatusForbidden, body: `{"error":{"code":403,"errors":[{"reason":"insufficientPermissions"}]}}`, expected: false},
+		{name: "not found", status: http.StatusNotFound, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := 0
+
+			q := gworkspace.NewGmailQuota([]gworkspace.GmailQuotaWindow{{Duration: time.Minute, Budget: 1000}})
+			gmailSvc, _ := newTestQuotaServices(t, q, func(w http.ResponseWriter, r *http.Request) {
+				sent++
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+
+			// the error is still passed on to the caller
+			_, err := gmailSvc.Users.History.List("me").Do()
+
+			var gerr *googleapi.Error
+			if !errors.As(err, &gerr) || gerr.Code != tt.status {
+				t.Fatalf("expected an api error with code %d, got %v", tt.status, err)
+			}
+
+			if backingOff := q.WouldExceed(1); backingOff != tt.expected {
+				t.Fatalf("expected backing off to be %v, got %v", tt.expected, backingOff)
+			}
+
+			if !tt.expected {
+				return
+			}
+
+			_, err = gmailSvc.Users.History.List("me").Do()
+			if !errors.Is(err, gworkspace.ErrGmailQuotaExceeded) {
+				t.Errorf("expected requests to be held back, got %v", err)
+			}
+
+			if sent != 1 || q.Usage().BackoffUntil.IsZero() {
+				t.Errorf("expected no requests to be sent while backing off, got %d sent", sent)
+			}
+		})
+	}
+}
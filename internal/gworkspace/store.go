@@ -0,0 +1,58 @@
+package gworkspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// readJsonFile decodes the contents of the file at path into v. ok is false if
+// the file does not exist yet
+func readJsonFile(path string, v any) (ok bool, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to open file (%s): %v", path, err)
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(v)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse file (%s): %v", path, err)
+	}
+
+	return true, nil
+}
+
+// writeJsonFile replaces the file at path with v encoded as json. the file is
+// written next to its final location and renamed so that a crash part way
+// through never leaves a truncated file behind
+func writeJsonFile(path string, v any) error {
+	tmpPath := path + ".tmp"
+
+	f, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open file (%s): %v", tmpPath, err)
+	}
+
+	err = json.NewEncoder(f).Encode(v)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write file (%s): %v", tmpPath, err)
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return fmt.Errorf("failed to replace file (%s): %v", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package gworkspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/api/tasks/v1"
+)
+
+const tasksStateFilePath = "tasks_state.json"
+
+// how often due reminders are checked for. due dates have no time of their
+// own, so this does not need to be precise
+const tasksReminderCheckFreq = time.Minute
+
+const TaskStatus_Completed = "completed"
+
+type TasksMonitorCfg struct {
+	UpdateFreq time.Duration
+
+	// DueAt is the time since midnight, in the local timezone, that tasks are
+	// due at on their due date. the api only keeps the date of due dates
+	DueAt time.Duration
+
+	// Offsets are how long before a task is due that it is reminded about. an
+	// offset of zero reminds when it is due
+	Offsets []time.Duration
+
+	// OverdueAt is the time since midnight that tasks that are past their due
+	// date are reminded about, once a day
+	OverdueAt time.Duration
+}
+
+type Task struct {
+	ListId   string
+	ListName string
+
+	Id    string
+	Title string
+	Notes string
+
+	// Due is when the task is due, the due date at DueAt
+	Due time.Time
+
+	WebViewLink string
+}
+
+type TaskReminder struct {
+	Task *Task
+
+	// Overdue is set for the daily reminder of a task that is past its due
+	// date
+	Overdue bool
+}
+
+type tasksState struct {
+	// Fired holds the keys of reminders that have already been sent, with
+	// when they can be forgotten
+	Fired map[string]time.Time
+}
+
+// TasksMonitor polls the tasks with due dates of every task list and reminds
+// about them before they are due and once a day while they are overdue
+type TasksMonitor struct {
+	mu    sync.Mutex
+	svc   *tasks.Service
+	cfg   TasksMonitorCfg
+	tasks []*Task
+	fired map[string]time.Time
+
+	remindersChan chan []*TaskReminder
+}
+
+func NewTasksMonitor(svc *tasks.Service, cfg TasksMonitorCfg) (*TasksMonitor, error) {
+	state := tasksState{Fired: make(map[string]time.Time)}
+	_, err := readJsonFile(tasksStateFilePath, &state)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading tasks state: %v", err)
+	}
+
+	if state.Fired == nil {
+		state.Fired = make(map[string]time.Time)
+	}
+
+	return &TasksMonitor{
+		svc:           svc,
+		cfg:           cfg,
+		fired:         state.Fired,
+		remindersChan: make(chan []*TaskReminder, 32),
+	}, nil
+}
+
+// Reminders receives the reminders of tasks as they become due
+func (m *TasksMonitor) Reminders() <-chan []*TaskReminder {
+	return m.remindersChan
+}
+
+func (m *TasksMonitor) Watch(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.UpdateFreq)
+	defer ticker.Stop()
+
+	reminderTicker := time.NewTicker(tasksReminderCheckFreq)
+	defer reminderTicker.Stop()
+
+	refresh := func() {
+		err := m.Refresh(ctx)
+		if err != nil {
+			slog.Error("error while fetching tasks", "error", err)
+		}
+	}
+
+	refresh()
+	m.checkDue(ctx, time.Now())
+
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-reminderTicker.C:
+			m.checkDue(ctx, time.Now())
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Refresh fetches the incomplete tasks with due dates of every task list
+func (m *TasksMonitor) Refresh(ctx context.Context) error {
+	lists := make([]*tasks.TaskList, 0)
+	err := m.svc.Tasklists.List().Context(ctx).Pages(ctx, func(res *tasks.TaskLists) error {
+		lists = append(lists, res.Items...)
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("error while listing task lists: %w", err)
+	}
+
+	var errs error
+	found := make([]*Task, 0)
+	for _, l := range lists {
+		err := m.svc.Tasks.List(l.Id).ShowCompleted(false).Context(ctx).Pages(ctx, func(res *tasks.Tasks) error {
+			for _, t := range res.Items {
+				if t.Due == "" || t.Deleted || t.Status == TaskStatus_Completed {
+					continue
+				}
+
+				due, err := time.Parse(time.RFC3339, t.Due)
+				if err != nil {
+					slog.Warn("skipping task with invalid due date", "taskId", t.Id, "due", t.Due, "error", err)
+					continue
+				}
+
+				found = append(found, &Task{
+					ListId:      l.Id,
+					ListName:    l.Title,
+					Id:          t.Id,
+					Title:       t.Title,
+					Notes:       t.Notes,
+					Due:         taskDueTime(due, m.cfg.DueAt),
+					WebViewLink: t.WebViewLink,
+				})
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("error while listing tasks (tasklist = %s): %w", l.Id, err))
+		}
+	}
+
+	// keep the tasks that could not be fetched rather than forgetting them
+	if errs != nil {
+		return errs
+	}
+
+	m.mu.Lock()
+	m.tasks = found
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Complete marks a task as completed and stops its reminders
+func (m *TasksMonitor) Complete(ctx context.Context, listId, taskId string) error {
+	_, err := m.svc.Tasks.Patch(listId, taskId, &tasks.Task{Status: TaskStatus_Completed}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error while completing task: %w", err)
+	}
+
+	m.mu.Lock()
+	m.tasks = slices.DeleteFunc(m.tasks, func(t *Task) bool { return t.ListId == listId && t.Id == taskId })
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *TasksMonitor) checkDue(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+
+	today := startOfDay(now)
+	due := make([]*TaskReminder, 0)
+	for _, t := range m.tasks {
+		key := t.ListId + "/" + t.Id + "/" + t.Due.Format(time.DateOnly)
+		dueDayEnd := startOfDay(t.Due).AddDate(0, 0, 1)
+
+		// overdue once the due date is over, from OverdueAt on
+		if !now.Before(dueDayEnd) {
+			if now.Before(today.Add(m.cfg.OverdueAt)) {
+				continue
+			}
+
+			overdueKey := key + "/overdue/" + today.Format(time.DateOnly)
+			if _, ok := m.fired[overdueKey]; ok {
+				continue
+			}
+
+			m.fired[overdueKey] = today.AddDate(0, 0, 1)
+			due = append(due, &TaskReminder{Task: t, Overdue: true})
+
+			continue
+		}
+
+		// a task is reminded about once even if several of its reminders are
+		// due at once, e.g. when the app was not running. reminders that were
+		// missed are still useful until the end of the due date
+		var fire []string
+		for _, o := range m.cfg.Offsets {
+			offsetKey := fmt.Sprintf("%s/%d", key, int64(o/time.Minute))
+			if _, ok := m.fired[offsetKey]; ok || now.Before(t.Due.Add(-o)) {
+				continue
+			}
+
+			fire = append(fire, offsetKey)
+		}
+
+		if len(fire) == 0 {
+			continue
+		}
+
+		for _, k := range fire {
+			m.fired[k] = dueDayEnd
+		}
+
+		due = append(due, &TaskReminder{Task: t})
+	}
+
+	changed := len(due) > 0
+	for key, forget := range m.fired {
+		if now.After(forget) {
+			delete(m.fired, key)
+			changed = true
+		}
+	}
+
+	var err error
+	if changed {
+		err = writeJsonFile(tasksStateFilePath, tasksState{Fired: m.fired})
+	}
+
+	m.mu.Unlock()
+
+	if err != nil {
+		slog.Error("error while saving tasks state", "error", err)
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	slog.Info("task reminders are due", "numReminders", len(due))
+
+	select {
+	case m.remindersChan <- due:
+	case <-ctx.Done():
+	}
+}
+
+// taskDueTime is the time a task is due, given the due date from the api. the
+// date is kept as midnight utc, regardless of the timezone of the user
+func taskDueTime(due time.Time, at time.Duration) time.Time {
+	due = due.UTC()
+	return time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.Local).Add(at)
+}
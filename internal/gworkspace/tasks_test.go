@@ -0,0 +1,140 @@
+package gworkspace_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"google.golang.org/api/tasks/v1"
+)
+
+// fakeTasks serves a single task list. patches to a task are recorded in
+// patched
+type fakeTasks struct {
+	tasks []*tasks.Task
+
+	patched *tasks.Task
+}
+
+func newTestTasksService(t *testing.T, f *fakeTasks) *tasks.Service {
+	opts := gworkspace.FakeApi(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/tasks/v1/users/@me/lists":
+			json.NewEncoder(w).Encode(&tasks.TaskLists{Items: []*tasks.TaskList{{Id: "list", Title: "My Tasks"}}})
+		case r.URL.Path == "/tasks/v1/lists/list/tasks":
+			json.NewEncoder(w).Encode(&tasks.Tasks{Items: f.tasks})
+		case strings.HasPrefix(r.URL.Path, "/tasks/v1/lists/list/tasks/") && r.Method == http.MethodPatch:
+			f.patched = &tasks.Task{}
+			json.NewDecoder(r.Body).Decode(f.patched)
+			json.NewEncoder(w).Encode(f.patched)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	svc, err := tasks.NewService(t.Context(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return svc
+}
+
+func receiveTaskReminders(m *gworkspace.TasksMonitor) []*gworkspace.TaskReminder {
+	select {
+	case r := <-m.Reminders():
+		return r
+	default:
+		return nil
+	}
+}
+
+func TestTasksMonitorReminders(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	f := &fakeTasks{tasks: []*tasks.Task{
+		{Id: "report", Title: "Send report", Due: "2026-10-20T00:00:00.000Z", Status: "needsAction"},
+		{Id: "undated", Title: "Someday", Status: "needsAction"},
+	}}
+
+	cfg := gworkspace.TasksMonitorCfg{DueAt: time.Hour * 9, Offsets: []time.Duration{time.Hour * 24, 0}, OverdueAt: time.Hour * 9}
+	m, err := gworkspace.NewTasksMonitor(newTestTasksService(t, f), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Refresh(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.Local)
+	}
+
+	steps := []struct {
+		now     time.Time
+		remind  bool
+		overdue bool
+	}{
+		{now: at(19, 8, 0)},
+		{now: at(19, 9, 30), remind: true},
+		{now: at(19, 10, 0)},
+		{now: at(20, 9, 0), remind: true},
+		{now: at(20, 23, 0)},
+		{now: at(21, 8, 0)},
+		{now: at(21, 9, 0), remind: true, overdue: true},
+		{now: at(21, 12, 0)},
+		{now: at(22, 9, 30), remind: true, overdue: true},
+	}
+
+	for _, s := range steps {
+		m.CheckDue(t.Context(), s.now)
+
+		got := receiveTaskReminders(m)
+		if !s.remind {
+			if got != nil {
+				t.Fatalf("expected no reminders at %v, got %d", s.now, len(got))
+			}
+
+			continue
+		}
+
+		if len(got) != 1 || got[0].Task.Id != "report" || got[0].Overdue != s.overdue {
+			t.Fatalf("expected a reminder (overdue = %v) at %v, got %v", s.overdue, s.now, got)
+		}
+	}
+
+	// a restarted monitor remembers the reminders that were sent
+	m, err = gworkspace.NewTasksMonitor(newTestTasksService(t, f), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Refresh(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.CheckDue(t.Context(), at(22, 10, 0))
+	if got := receiveTaskReminders(m); got != nil {
+		t.Fatalf("expected no reminders after a restart, got %d", len(got))
+	}
+
+	err = m.Complete(t.Context(), "list", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.patched == nil || f.patched.Status != gworkspace.TaskStatus_Completed {
+		t.Fatalf("expected the task to be completed, got %+v", f.patched)
+	}
+
+	m.CheckDue(t.Context(), at(23, 10, 0))
+	if got := receiveTaskReminders(m); got != nil {
+		t.Fatalf("expected no reminders for a completed task, got %d", len(got))
+	}
+}
@@ -0,0 +1,270 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+)
+
+var (
+	ErrEntryNotFound = errors.New("history entry not found")
+	ErrNotAMessage   = errors.New("history entry does not refer to a gmail message")
+	ErrNotAnEvent    = errors.New("history entry does not refer to a calendar event")
+)
+
+// Entry is a notification that was shown to the user
+type Entry struct {
+	Id    uint64
+	Time  time.Time
+	Title string
+	Body  string
+
+	// Message is the gmail message that triggered the notification, if any
+	Message *gworkspace.GmailMessage
+
+	// Labels is our view of the labels currently on Message. it is updated
+	// optimistically when actions are applied
+	Labels []string
+
+	// Event is the calendar event that triggered the notification, if any
+	Event *gworkspace.CalendarEvent
+}
+
+func (e *Entry) HasLabel(label string) bool {
+	return slices.Contains(e.Labels, label)
+}
+
+// History keeps the most recent notifications in memory, dropping the oldest
+// entries once capacity is reached
+type History struct {
+	mu       sync.Mutex
+	capacity int
+	nextId   uint64
+	entries  []*Entry
+
+	cChanged chan struct{}
+}
+
+func NewHistory(capacity int) *History {
+	return &History{
+		capacity: capacity,
+		nextId:   1,
+		entries:  make([]*Entry, 0, capacity),
+		cChanged: make(chan struct{}, 1),
+	}
+}
+
+func (h *History) Add(title, body string, msg *gworkspace.GmailMessage) Entry {
+	e := &Entry{Title: title, Body: body, Message: msg}
+	if msg != nil {
+		e.Labels = slices.Clone(msg.LabelIds)
+	}
+
+	return h.add(e)
+}
+
+// AddEvent adds an entry for a calendar event
+func (h *History) AddEvent(title, body string, ev *gworkspace.CalendarEvent) Entry {
+	return h.add(&Entry{Title: title, Body: body, Event: ev})
+}
+
+func (h *History) add(e *Entry) Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e.Id = h.nextId
+	e.Time = time.Now()
+	h.nextId++
+
+	if len(h.entries) >= h.capacity {
+		h.entries = slices.Delete(h.entries, 0, len(h.entries)-h.capacity+1)
+	}
+	h.entries = append(h.entries, e)
+
+	h.notifyChanged()
+
+	return e.clone()
+}
+
+func (h *History) Get(id uint64) (Entry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e := h.find(id)
+	if e == nil {
+		return Entry{}, false
+	}
+
+	return e.clone(), true
+}
+
+// FindMessage returns the newest entry for the gmail message with the id
+func (h *History) FindMessage(msgId string) (Entry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, e := range slices.Backward(h.entries) {
+		if e.Message != nil && e.Message.Id == msgId {
+			return e.clone(), true
+		}
+	}
+
+	return Entry{}, false
+}
+
+// Entries returns a copy of all entries, newest first
+func (h *History) Entries() []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := make([]Entry, 0, len(h.entries))
+	for _, e := range slices.Backward(h.entries) {
+		entries = append(entries, e.clone())
+	}
+
+	return entries
+}
+
+// Changed receives a value whenever entries are added or modified
+func (h *History) Changed() <-chan struct{} {
+	return h.cChanged
+}
+
+// ApplyGmailAction applies action to the message of the entry. the entry is
+// updated before the request is made so that the change is visible straight
+// away, and rolled back if the request fails
+func (h *History) ApplyGmailAction(ctx context.Context, actions *gworkspace.GmailActions, id uint64, action gworkspace.GmailAction) error {
+	h.mu.Lock()
+
+	e := h.find(id)
+	if e == nil {
+		h.mu.Unlock()
+		return ErrEntryNotFound
+	}
+
+	if e.Message == nil {
+		h.mu.Unlock()
+		return ErrNotAMessage
+	}
+
+	msg := e.Message
+	added, removed := applyLabelChanges(e, action)
+	h.notifyChanged()
+
+	h.mu.Unlock()
+
+	err := actions.ApplyToMessage(ctx, action, msg)
+	if err != nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		// only undo the labels this action actually changed so that the
+		// result of any other action applied in the meantime is kept
+		if e := h.find(id); e != nil {
+			e.Labels = slices.DeleteFunc(e.Labels, func(l string) bool { return slices.Contains(added, l) })
+			for _, l := range removed {
+				if !e.HasLabel(l) {
+					e.Labels = append(e.Labels, l)
+				}
+			}
+
+			h.notifyChanged()
+		}
+
+		return fmt.Errorf("error while applying %s: %v", action, err)
+	}
+
+	return nil
+}
+
+// RespondToEvent responds to the invitation to the event of the entry. like
+// ApplyGmailAction, the entry shows the response straight away and is rolled
+// back if the request fails
+func (h *History) RespondToEvent(ctx context.Context, cal *gworkspace.CalendarMonitor, id uint64, rsvp gworkspace.CalendarRsvp, comment string) error {
+	h.mu.Lock()
+
+	e := h.find(id)
+	if e == nil {
+		h.mu.Unlock()
+		return ErrEntryNotFound
+	}
+
+	if e.Event == nil {
+		h.mu.Unlock()
+		return ErrNotAnEvent
+	}
+
+	prev := e.Event
+	ev := *prev
+	ev.ResponseStatus = string(rsvp)
+	e.Event = &ev
+	h.notifyChanged()
+
+	h.mu.Unlock()
+
+	err := cal.Respond(ctx, prev.CalendarId, prev.Id, rsvp, comment)
+	if err != nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		// leave the entry alone if another response was made in the meantime
+		if e := h.find(id); e != nil && e.Event == &ev {
+			e.Event = prev
+			h.notifyChanged()
+		}
+
+		return fmt.Errorf("error while responding %s: %v", rsvp.Title(), err)
+	}
+
+	return nil
+}
+
+func (h *History) find(id uint64) *Entry {
+	for _, e := range h.entries {
+		if e.Id == id {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func (h *History) notifyChanged() {
+	select {
+	case h.cChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (e *Entry) clone() Entry {
+	c := *e
+	c.Labels = slices.Clone(e.Labels)
+	return c
+}
+
+// applyLabelChanges updates the labels of e for action and returns the labels
+// that were actually added and removed
+func applyLabelChanges(e *Entry, action gworkspace.GmailAction) (added []string, removed []string) {
+	add, remove := action.LabelChanges()
+
+	for _, l := range remove {
+		if e.HasLabel(l) {
+			e.Labels = slices.DeleteFunc(e.Labels, func(x string) bool { return x == l })
+			removed = append(removed, l)
+		}
+	}
+
+	for _, l := range add {
+		if !e.HasLabel(l) {
+			e.Labels = append(e.Labels, l)
+			added = append(added, l)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,82 @@
+package history_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+func newTestActions(t *testing.T, status int) *gworkspace.GmailActions {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte("{}"))
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := gmail.NewService(t.Context(), option.WithHTTPClient(srv.Client()), option.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatalf("failed to create gmail service: %v", err)
+	}
+
+	t.Chdir(t.TempDir())
+
+	acts, err := gworkspace.NewGmailActions(svc)
+	if err != nil {
+		t.Fatalf("failed to create gmail actions: %v", err)
+	}
+
+	return acts
+}
+
+func TestApplyGmailActionUpdatesLabels(t *testing.T) {
+	acts := newTestActions(t, http.StatusOK)
+
+	h := history.NewHistory(10)
+	e := h.Add("title", "body", &gworkspace.GmailMessage{Id: "m1", ThreadId: "t1", LabelIds: []string{"INBOX", "UNREAD"}})
+
+	err := h.ApplyGmailAction(t.Context(), acts, e.Id, gworkspace.GmailAction_MarkRead)
+	if err != nil {
+		t.Fatalf("ApplyGmailAction returned error: %v", err)
+	}
+
+	e, _ = h.Get(e.Id)
+	if !slices.Equal(e.Labels, []string{"INBOX"}) {
+		t.Errorf("expected labels [INBOX] after marking as read, got %v", e.Labels)
+	}
+}
+
+func TestApplyGmailActionRollsBackOnError(t *testing.T) {
+	acts := newTestActions(t, http.StatusInternalServerError)
+
+	h := history.NewHistory(10)
+	e := h.Add("title", "body", &gworkspace.GmailMessage{Id: "m1", ThreadId: "t1", LabelIds: []string{"INBOX", "UNREAD"}})
+
+	err := h.ApplyGmailAction(t.Context(), acts, e.Id, gworkspace.GmailAction_Archive)
+	if err == nil {
+		t.Fatalf("expected ApplyGmailAction to return an error")
+	}
+
+	e, _ = h.Get(e.Id)
+	if !e.HasLabel("INBOX") || !e.HasLabel("UNREAD") {
+		t.Errorf("expected labels to be rolled back to [INBOX UNREAD], got %v", e.Labels)
+	}
+}
+
+func TestHistoryDropsOldestEntries(t *testing.T) {
+	h := history.NewHistory(2)
+	h.Add("1", "", nil)
+	h.Add("2", "", nil)
+	h.Add("3", "", nil)
+
+	entries := h.Entries()
+	if len(entries) != 2 || entries[0].Title != "3" || entries[1].Title != "2" {
+		t.Errorf("expected entries [3 2], got %v", entries)
+	}
+}
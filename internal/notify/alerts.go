@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"log/slog"
+
+	"github.com/link00000000/gwsn/internal/browser"
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/sysnotif"
+)
+
+// Alert describes a workspace alert
+func Alert(a *gworkspace.Alert) (title, body string) {
+	title = "Security alert: " + a.Type
+
+	severity := "Unknown"
+	switch a.Severity {
+	case gworkspace.AlertSeverity_High:
+		severity = "High"
+	case gworkspace.AlertSeverity_Medium:
+		severity = "Medium"
+	case gworkspace.AlertSeverity_Low:
+		severity = "Low"
+	}
+
+	body = "Severity: " + severity
+	if a.Source != "" {
+		body += "\nSource: " + a.Source
+	}
+
+	return title, body
+}
+
+// AlertUrgency is the urgency of the notification for an alert. high severity
+// alerts stay on screen, and are shown during do not disturb
+func AlertUrgency(severity string) sysnotif.Urgency {
+	switch severity {
+	case gworkspace.AlertSeverity_High:
+		return sysnotif.Urgency_Critical
+	case gworkspace.AlertSeverity_Low:
+		return sysnotif.Urgency_Low
+	}
+
+	return sysnotif.Urgency_Normal
+}
+
+// ShowAlert shows an alert with an action to open it in the admin console
+func ShowAlert(a *gworkspace.Alert, title, body string, urgency sysnotif.Urgency) {
+	sysnotif.Show(&sysnotif.Notification{
+		Title:   title,
+		Message: body,
+		Urgency: urgency,
+		Actions: []sysnotif.Action{{Key: openActionKey, Label: "Open in admin console"}},
+		OnAction: func(key string) {
+			if key != openActionKey {
+				return
+			}
+
+			err := browser.Open(a.Link)
+			if err != nil {
+				slog.Error("failed to open alert", "alertId", a.Id, "error", err)
+				sysnotif.ShowNotification("Failed to open alert", err.Error())
+			}
+		},
+	})
+}
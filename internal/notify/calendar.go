@@ -0,0 +1,266 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/browser"
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/sysnotif"
+)
+
+// prefix of the keys of notification actions that respond to an invitation
+const rsvpActionKeyPrefix = "rsvp:"
+
+const joinActionKey = "join"
+
+// number of attendees named in a reminder before the rest are summarized
+const maxReminderAttendees = 3
+
+// number of characters of an event summary shown on a notification button
+const maxActionSummaryLen = 20
+
+// CalendarReminder describes an event that is about to start
+func CalendarReminder(ev *gworkspace.CalendarEvent) (title, body string) {
+	title = ev.Summary
+	if title == "" {
+		title = "(No title)"
+	}
+
+	if ev.AllDay {
+		body = ev.Start.Format("Mon Jan 2") + " (all day)"
+	} else {
+		body = "Starts at " + ev.Start.Local().Format("15:04")
+	}
+
+	if ev.Location != "" {
+		body += " · " + ev.Location
+	}
+
+	if len(ev.Attendees) > 0 {
+		names := ev.Attendees[:min(len(ev.Attendees), maxReminderAttendees)]
+		body += "\nWith " + strings.Join(names, ", ")
+
+		if more := len(ev.Attendees) - len(names); more > 0 {
+			body += fmt.Sprintf(" and %d more", more)
+		}
+	}
+
+	return title, body
+}
+
+// ShowReminder shows a reminder for an event with an action to join the call if
+// it has one
+func ShowReminder(ev *gworkspace.CalendarEvent, title, body string) {
+	n := &sysnotif.Notification{Title: title, Message: body}
+
+	if ev.ConferenceLink != "" {
+		n.Actions = append(n.Actions, sysnotif.Action{Key: joinActionKey, Label: "Join"})
+		n.OnAction = func(key string) {
+			if key == joinActionKey {
+				JoinMeeting(ev.ConferenceLink)
+			}
+		}
+	}
+
+	sysnotif.Show(n)
+}
+
+// JoinMeeting opens the link of a call in the browser and lets the user know if
+// it failed
+func JoinMeeting(link string) {
+	err := browser.Open(link)
+	if err != nil {
+		slog.Error("failed to open meeting link", "link", link, "error", err)
+		sysnotif.ShowNotification("Failed to join meeting", err.Error())
+	}
+}
+
+// CalendarChange describes a change to an event. events the user added
+// themselves and updates that change nothing worth showing are not notified
+func CalendarChange(c *gworkspace.CalendarEventChange) (title, body string, ok bool) {
+	ev := c.Event
+
+	summary := ev.Summary
+	if summary == "" {
+		summary = "(No title)"
+	}
+
+	when := "Starts at " + ev.Start.Local().Format("Mon Jan 2 15:04")
+	if ev.AllDay {
+		when = ev.Start.Format("Mon Jan 2") + " (all day)"
+	}
+
+	switch c.Kind {
+	case gworkspace.CalendarChangeKind_Invited:
+		body = when
+		if ev.Location != "" {
+			body += " · " + ev.Location
+		}
+
+		if ev.Organizer != "" {
+			body += "\nFrom " + ev.Organizer
+		}
+
+		return "Invitation: " + summary, body, true
+	case gworkspace.CalendarChangeKind_Updated:
+		diff := gworkspace.DiffCalendarEvents(c.Previous, ev)
+		if len(diff) == 0 {
+			return "", "", false
+		}
+
+		lines := make([]string, len(diff))
+		for i, d := range diff {
+			lines[i] = summary + " " + d.Description
+		}
+
+		return "Updated: " + summary, strings.Join(lines, "\n"), true
+	case gworkspace.CalendarChangeKind_Cancelled:
+		return "Cancelled: " + summary, when, true
+	}
+
+	return "", "", false
+}
+
+// ShowInvitation shows a notification for the invitation of the history entry
+// with an action for each response
+func ShowInvitation(ctx context.Context, hist *history.History, cal *gworkspace.CalendarMonitor, id uint64, title, body string) {
+	n := &sysnotif.Notification{
+		Title:   title,
+		Message: body,
+		OnAction: func(key string) {
+			rsvp, err := gworkspace.ParseCalendarRsvp(strings.TrimPrefix(key, rsvpActionKeyPrefix))
+			if err != nil {
+				slog.Warn("unknown notification rsvp", "key", key)
+				return
+			}
+
+			respondToEvent(ctx, hist, cal, id, rsvp)
+		},
+	}
+
+	for _, rsvp := range gworkspace.AllCalendarRsvps {
+		n.Actions = append(n.Actions, sysnotif.Action{Key: rsvpActionKeyPrefix + string(rsvp), Label: rsvp.Title()})
+	}
+
+	sysnotif.Show(n)
+}
+
+// respondToEvent responds to an invitation from a notification and lets the
+// user know if it failed
+func respondToEvent(ctx context.Context, hist *history.History, cal *gworkspace.CalendarMonitor, id uint64, rsvp gworkspace.CalendarRsvp) {
+	err := hist.RespondToEvent(ctx, cal, id, rsvp, "")
+	if err != nil {
+		slog.Error("failed to respond to calendar event", "id", id, "response", rsvp, "error", err)
+		sysnotif.ShowNotification("Failed to respond "+rsvp.Title(), err.Error())
+	}
+}
+
+// CalendarConflict describes two overlapping events and the calendars they are
+// on
+func CalendarConflict(c gworkspace.CalendarConflict, calendarName func(id string) string) (title, body string) {
+	describe := func(ev *gworkspace.CalendarEvent) (string, string) {
+		summary := ev.Summary
+		if summary == "" {
+			summary = "(No title)"
+		}
+
+		when := ev.Start.Local().Format("Mon Jan 2 15:04") + "–" + ev.End.Local().Format("15:04")
+		return summary, when + " " + summary + " (" + calendarName(ev.CalendarId) + ")"
+	}
+
+	a, aLine := describe(c.A)
+	b, bLine := describe(c.B)
+
+	return "Conflict: " + a + " and " + b, aLine + "\n" + bLine
+}
+
+// ShowConflict shows a conflict with an action to open each of the events in
+// the browser
+func ShowConflict(c gworkspace.CalendarConflict, title, body string) {
+	events := map[string]*gworkspace.CalendarEvent{"open:a": c.A, "open:b": c.B}
+
+	n := &sysnotif.Notification{
+		Title:   title,
+		Message: body,
+		OnAction: func(key string) {
+			if ev, ok := events[key]; ok {
+				openEvent(ev)
+			}
+		},
+	}
+
+	for _, key := range []string{"open:a", "open:b"} {
+		ev := events[key]
+		if ev.HtmlLink == "" {
+			continue
+		}
+
+		label := ev.Summary
+		if len([]rune(label)) > maxActionSummaryLen {
+			label = string([]rune(label)[:maxActionSummaryLen-1]) + "…"
+		}
+
+		n.Actions = append(n.Actions, sysnotif.Action{Key: key, Label: "Open " + label})
+	}
+
+	sysnotif.Show(n)
+}
+
+// openEvent opens an event in google calendar and lets the user know if it
+// failed
+func openEvent(ev *gworkspace.CalendarEvent) {
+	err := browser.Open(ev.HtmlLink)
+	if err != nil {
+		slog.Error("failed to open calendar event", "eventId", ev.Id, "error", err)
+		sysnotif.ShowNotification("Failed to open event", err.Error())
+	}
+}
+
+// CalendarDigest summarises the agenda of the day. times are shown in loc, the
+// timezone of the account
+func CalendarDigest(agenda *gworkspace.CalendarAgenda, loc *time.Location) (title, body string) {
+	clock := func(t time.Time) string { return t.In(loc).Format("15:04") }
+
+	switch len(agenda.Events) {
+	case 0:
+		title = "Today: no meetings"
+	case 1:
+		title = "Today: 1 meeting"
+	default:
+		title = fmt.Sprintf("Today: %d meetings", len(agenda.Events))
+	}
+
+	lines := make([]string, 0, len(agenda.Events)+2)
+	for _, ev := range agenda.Events {
+		summary := ev.Summary
+		if summary == "" {
+			summary = "(No title)"
+		}
+
+		if ev.AllDay {
+			lines = append(lines, "All day "+summary)
+		} else {
+			lines = append(lines, clock(ev.Start)+"–"+clock(ev.End)+" "+summary)
+		}
+	}
+
+	if len(agenda.Free) > 0 {
+		free := make([]string, len(agenda.Free))
+		for i, b := range agenda.Free {
+			free[i] = clock(b.Start) + "–" + clock(b.End)
+		}
+
+		lines = append(lines, "Free "+strings.Join(free, ", "))
+	}
+
+	for _, c := range agenda.Conflicts {
+		lines = append(lines, fmt.Sprintf("Conflict: %s and %s", c.A.Summary, c.B.Summary))
+	}
+
+	return title, strings.Join(lines, "\n")
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+)
+
+// number of messages of a chat conversation shown in a notification before the
+// rest are summarized
+const maxChatNotificationLines = 3
+
+// Chat describes the new messages of a chat conversation
+func Chat(c *gworkspace.ChatConversation) (title, body string) {
+	title = c.Space.DisplayName
+	if c.Space.Direct && title == "" {
+		title = c.Messages[0].Sender
+	}
+
+	if len(c.Messages) > 1 {
+		title = fmt.Sprintf("%s (%d messages)", title, len(c.Messages))
+	}
+
+	lines := make([]string, 0, maxChatNotificationLines+1)
+	for _, msg := range c.Messages[:min(len(c.Messages), maxChatNotificationLines)] {
+		lines = append(lines, msg.Sender+": "+msg.Text)
+	}
+
+	if more := len(c.Messages) - len(lines); more > 0 {
+		lines = append(lines, fmt.Sprintf("and %d more", more))
+	}
+
+	return title, strings.Join(lines, "\n")
+}
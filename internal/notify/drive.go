@@ -0,0 +1,61 @@
+package notify
+
+import "github.com/link00000000/gwsn/internal/gworkspace"
+
+// DriveShared describes a file that was shared with the user
+func DriveShared(f *gworkspace.DriveFile) (title, body string) {
+	who := f.SharedBy
+	if who == "" {
+		who = f.Owner
+	}
+
+	if who == "" {
+		title = "Shared with you: " + f.Name
+	} else {
+		title = who + " shared " + f.Name
+	}
+
+	body = driveFileKind(f.MimeType)
+	if f.Owner != "" {
+		body += ", owned by " + f.Owner
+	}
+
+	return title, body
+}
+
+// DriveComment describes a comment that mentions the user or assigns them an
+// action item, quoting it along with the text it is on
+func DriveComment(c *gworkspace.DriveComment) (title, body string) {
+	if c.Assigned {
+		title = c.Author + " assigned you an action item in " + c.FileName
+	} else {
+		title = c.Author + " mentioned you in " + c.FileName
+	}
+
+	body = "“" + c.Content + "”"
+	if c.Quote != "" {
+		body += "\nOn: " + c.Quote
+	}
+
+	return title, body
+}
+
+// driveFileKind is the name of the kind of file of a mime type
+func driveFileKind(mimeType string) string {
+	switch mimeType {
+	case "application/vnd.google-apps.document":
+		return "Google Doc"
+	case "application/vnd.google-apps.spreadsheet":
+		return "Google Sheet"
+	case "application/vnd.google-apps.presentation":
+		return "Google Slides"
+	case "application/vnd.google-apps.form":
+		return "Google Form"
+	case "application/vnd.google-apps.folder":
+		return "Folder"
+	case "application/pdf":
+		return "PDF"
+	}
+
+	return mimeType
+}
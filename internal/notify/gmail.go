@@ -0,0 +1,95 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/sysnotif"
+)
+
+// notification actions offered for new messages. notification servers only show
+// a few buttons, so the remaining actions are left to the tray and web ui
+var messageNotificationActions = []gworkspace.GmailAction{
+	gworkspace.GmailAction_MarkRead,
+	gworkspace.GmailAction_Archive,
+	gworkspace.GmailAction_Trash,
+}
+
+var messageNotificationSnoozeOptions = []gworkspace.GmailSnoozeOption{
+	gworkspace.GmailSnoozeOption_TwoHours,
+	gworkspace.GmailSnoozeOption_TomorrowMorning,
+}
+
+// notification action keys for snooze options are prefixed to tell them apart
+// from gmail actions
+const snoozeActionKeyPrefix = "snooze:"
+
+// ShowMessage shows a notification for the message of the history entry with
+// actions that apply to it
+func ShowMessage(ctx context.Context, hist *history.History, acts *gworkspace.GmailActions, snoozer *gworkspace.GmailSnoozer, id uint64, title, body string, urgency sysnotif.Urgency) {
+	n := &sysnotif.Notification{
+		Title:   title,
+		Message: body,
+		Urgency: urgency,
+		OnAction: func(key string) {
+			if s, ok := strings.CutPrefix(key, snoozeActionKeyPrefix); ok {
+				option, err := gworkspace.ParseGmailSnoozeOption(s)
+				if err != nil {
+					slog.Warn("unknown notification snooze option", "key", key)
+					return
+				}
+
+				SnoozeMessage(hist, snoozer, id, option)
+				return
+			}
+
+			action, err := gworkspace.ParseGmailAction(key)
+			if err != nil {
+				slog.Warn("unknown notification action", "key", key)
+				return
+			}
+
+			ApplyGmailAction(ctx, hist, acts, id, action)
+		},
+	}
+
+	for _, action := range messageNotificationActions {
+		n.Actions = append(n.Actions, sysnotif.Action{Key: string(action), Label: action.Title()})
+	}
+
+	for _, option := range messageNotificationSnoozeOptions {
+		n.Actions = append(n.Actions, sysnotif.Action{Key: snoozeActionKeyPrefix + string(option), Label: option.Title()})
+	}
+
+	sysnotif.Show(n)
+}
+
+// ApplyGmailAction applies an action picked from a notification or the tray and
+// lets the user know if it failed, since neither can show errors inline
+func ApplyGmailAction(ctx context.Context, hist *history.History, acts *gworkspace.GmailActions, id uint64, action gworkspace.GmailAction) {
+	err := hist.ApplyGmailAction(ctx, acts, id, action)
+	if err != nil {
+		slog.Error("failed to apply gmail action", "id", id, "action", action, "error", err)
+		sysnotif.ShowNotification("Failed to "+strings.ToLower(action.Title()), err.Error())
+	}
+}
+
+// SnoozeMessage snoozes the notification for the message of a history entry and
+// lets the user know if it failed
+func SnoozeMessage(hist *history.History, snoozer *gworkspace.GmailSnoozer, id uint64, option gworkspace.GmailSnoozeOption) {
+	e, ok := hist.Get(id)
+	if !ok || e.Message == nil {
+		slog.Warn("attempted to snooze history entry that is not a message", "id", id)
+		return
+	}
+
+	err := snoozer.Snooze(e.Message, e.Title, e.Body, option.WakeAt(time.Now()))
+	if err != nil {
+		slog.Error("failed to snooze message", "id", id, "option", option, "error", err)
+		sysnotif.ShowNotification("Failed to snooze message", err.Error())
+	}
+}
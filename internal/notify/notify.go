@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/link00000000/gwsn/internal/browser"
+	"github.com/link00000000/gwsn/internal/dnd"
+	"github.com/link00000000/gwsn/internal/sysnotif"
+)
+
+const openActionKey = "open"
+
+// ShowLink shows a notification with an action to open link in the browser, if
+// there is one
+func ShowLink(title, body, link string) {
+	n := &sysnotif.Notification{Title: title, Message: body}
+
+	if link != "" {
+		n.Actions = append(n.Actions, sysnotif.Action{Key: openActionKey, Label: "Open"})
+		n.OnAction = func(key string) {
+			if key != openActionKey {
+				return
+			}
+
+			err := browser.Open(link)
+			if err != nil {
+				slog.Error("failed to open link", "link", link, "error", err)
+				sysnotif.ShowNotification("Failed to open link", err.Error())
+			}
+		}
+	}
+
+	sysnotif.Show(n)
+}
+
+// number of held back notifications listed in the summary shown when do not
+// disturb is turned off
+const maxDeferredSummaryLines = 5
+
+// DeferredSummary summarises the notifications held back while do not disturb
+// was on
+func DeferredSummary(deferred []dnd.Deferred) (title, body string) {
+	title = "1 notification held back"
+	if len(deferred) > 1 {
+		title = fmt.Sprintf("%d notifications held back", len(deferred))
+	}
+
+	lines := make([]string, 0, maxDeferredSummaryLines+1)
+	for _, d := range deferred[:min(len(deferred), maxDeferredSummaryLines)] {
+		lines = append(lines, d.Title)
+	}
+
+	if more := len(deferred) - len(lines); more > 0 {
+		lines = append(lines, fmt.Sprintf("and %d more", more))
+	}
+
+	return title, strings.Join(lines, "\n")
+}
@@ -0,0 +1,70 @@
+package notify_test
+
+import (
+	"testing"
+
+	"github.com/link00000000/gwsn/internal/dnd"
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/notify"
+)
+
+func TestChat(t *testing.T) {
+	msg := func(sender, text string) *gworkspace.ChatMessage {
+		return &gworkspace.ChatMessage{Sender: sender, Text: text}
+	}
+
+	tests := []struct {
+		name          string
+		conv          *gworkspace.ChatConversation
+		expectedTitle string
+		expectedBody  string
+	}{
+		{
+			name:          "direct message",
+			conv:          &gworkspace.ChatConversation{Space: gworkspace.ChatSpace{Direct: true}, Messages: []*gworkspace.ChatMessage{msg("Alice", "hi")}},
+			expectedTitle: "Alice",
+			expectedBody:  "Alice: hi",
+		},
+		{
+			name: "long conversation in a space",
+			conv: &gworkspace.ChatConversation{Space: gworkspace.ChatSpace{DisplayName: "Team"}, Messages: []*gworkspace.ChatMessage{
+				msg("Alice", "1"), msg("Bob", "2"), msg("Alice", "3"), msg("Bob", "4"), msg("Alice", "5"),
+			}},
+			expectedTitle: "Team (5 messages)",
+			expectedBody:  "Alice: 1\nBob: 2\nAlice: 3\nand 2 more",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, body := notify.Chat(tt.conv)
+			if title != tt.expectedTitle || body != tt.expectedBody {
+				t.Fatalf("expected %q %q, got %q %q", tt.expectedTitle, tt.expectedBody, title, body)
+			}
+		})
+	}
+}
+
+func TestDeferredSummary(t *testing.T) {
+	tests := []struct {
+		count         int
+		expectedTitle string
+		expectedBody  string
+	}{
+		{count: 1, expectedTitle: "1 notification held back", expectedBody: "0"},
+		{count: 5, expectedTitle: "5 notifications held back", expectedBody: "0\n1\n2\n3\n4"},
+		{count: 7, expectedTitle: "7 notifications held back", expectedBody: "0\n1\n2\n3\n4\nand 2 more"},
+	}
+
+	for _, tt := range tests {
+		deferred := make([]dnd.Deferred, tt.count)
+		for i := range deferred {
+			deferred[i] = dnd.Deferred{Title: string(rune('0' + i))}
+		}
+
+		title, body := notify.DeferredSummary(deferred)
+		if title != tt.expectedTitle || body != tt.expectedBody {
+			t.Errorf("DeferredSummary of %d: expected %q %q, got %q %q", tt.count, tt.expectedTitle, tt.expectedBody, title, body)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/sysnotif"
+)
+
+const completeTaskActionKey = "complete"
+
+// TaskReminder describes a task that is due or overdue
+func TaskReminder(r *gworkspace.TaskReminder) (title, body string) {
+	title = r.Task.Title
+	if title == "" {
+		title = "(No title)"
+	}
+
+	if r.Overdue {
+		title = "Overdue: " + title
+	} else {
+		title = "Due " + r.Task.Due.Format("Mon Jan 2 15:04") + ": " + title
+	}
+
+	body = r.Task.ListName
+	if r.Task.Notes != "" {
+		body += "\n" + r.Task.Notes
+	}
+
+	return title, body
+}
+
+// ShowTask shows a reminder for a task with an action to mark it as complete
+func ShowTask(ctx context.Context, m *gworkspace.TasksMonitor, t *gworkspace.Task, title, body string) {
+	sysnotif.Show(&sysnotif.Notification{
+		Title:   title,
+		Message: body,
+		Actions: []sysnotif.Action{{Key: completeTaskActionKey, Label: "Mark complete"}},
+		OnAction: func(key string) {
+			if key != completeTaskActionKey {
+				return
+			}
+
+			err := m.Complete(ctx, t.ListId, t.Id)
+			if err != nil {
+				slog.Error("failed to complete task", "taskId", t.Id, "error", err)
+				sysnotif.ShowNotification("Failed to complete task", err.Error())
+			}
+		},
+	})
+}
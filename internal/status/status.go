@@ -0,0 +1,149 @@
+package status
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+)
+
+// Status is the state of the monitors that is shown in the tray and the web
+// ui
+type Status struct {
+	mu sync.Mutex
+
+	unreadCounts []gworkspace.GmailLabelCounts
+	quotaUsage   gworkspace.GmailQuotaUsage
+	offline      bool
+	nextEvent    *gworkspace.CalendarEvent
+	dndReason    string
+
+	cChanged chan struct{}
+}
+
+func NewStatus() *Status {
+	return &Status{
+		cChanged: make(chan struct{}, 1),
+	}
+}
+
+// Changed receives a value whenever the status is modified
+func (s *Status) Changed() <-chan struct{} {
+	return s.cChanged
+}
+
+func (s *Status) UnreadCounts() []gworkspace.GmailLabelCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.unreadCounts)
+}
+
+func (s *Status) SetUnreadCounts(counts []gworkspace.GmailLabelCounts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.Equal(s.unreadCounts, counts) {
+		return
+	}
+
+	s.unreadCounts = slices.Clone(counts)
+	s.notifyChanged()
+}
+
+func (s *Status) QuotaUsage() gworkspace.GmailQuotaUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.quotaUsage
+}
+
+// SetQuotaUsage updates the quota usage. usage changes with every request, so
+// Changed is not notified
+func (s *Status) SetQuotaUsage(usage gworkspace.GmailQuotaUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quotaUsage = usage
+}
+
+// Offline reports whether gmail could not be reached
+func (s *Status) Offline() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.offline
+}
+
+func (s *Status) SetOffline(offline bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.offline == offline {
+		return
+	}
+
+	s.offline = offline
+	s.notifyChanged()
+}
+
+// NextEvent is the meeting that is in progress or starts next, or nil
+func (s *Status) NextEvent() *gworkspace.CalendarEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.nextEvent
+}
+
+func (s *Status) SetNextEvent(ev *gworkspace.CalendarEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nextEvent == ev || (s.nextEvent != nil && ev != nil && s.nextEvent.Equal(ev)) {
+		return
+	}
+
+	s.nextEvent = ev
+	s.notifyChanged()
+}
+
+// DndReason is why do not disturb is on, or empty if it is off
+func (s *Status) DndReason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dndReason
+}
+
+func (s *Status) SetDndReason(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dndReason == reason {
+		return
+	}
+
+	s.dndReason = reason
+	s.notifyChanged()
+}
+
+// TotalUnread is the number of unread messages across all watched labels. a
+// message in several labels is counted once for each
+func (s *Status) TotalUnread() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, c := range s.unreadCounts {
+		n += c.MessagesUnread
+	}
+
+	return n
+}
+
+func (s *Status) notifyChanged() {
+	select {
+	case s.cChanged <- struct{}{}:
+	default:
+	}
+}
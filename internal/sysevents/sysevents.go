@@ -0,0 +1,128 @@
+package sysevents
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultClockCheckFreq is how often the wall clock is usually compared
+// against the monotonic clock
+const DefaultClockCheckFreq = time.Second * 5
+
+// DefaultClockJumpThreshold is how far the wall clock usually has to get ahead
+// of the monotonic clock between two checks to be treated as a resume
+const DefaultClockJumpThreshold = time.Second * 10
+
+type Event string
+
+const (
+	Event_Resume         Event = "resume"
+	Event_NetworkChanged Event = "network_changed"
+)
+
+// Clock reads the wall clock and the monotonic clock
+type Clock interface {
+	// Now returns the wall clock time and the time elapsed on the monotonic
+	// clock since some fixed point
+	Now() (wall time.Time, monotonic time.Duration)
+}
+
+type systemClock struct {
+	start time.Time
+}
+
+func (c systemClock) Now() (time.Time, time.Duration) {
+	now := time.Now()
+	return now.Round(0), now.Sub(c.start)
+}
+
+type WatcherCfg struct {
+	// ClockCheckFreq is how often the wall clock is compared against the
+	// monotonic clock
+	ClockCheckFreq time.Duration
+
+	// ClockJumpThreshold is how far the wall clock must get ahead of the
+	// monotonic clock between two checks to be treated as a resume
+	ClockJumpThreshold time.Duration
+
+	// Clock is the clock that is watched. may be nil to watch the system clock
+	Clock Clock
+}
+
+// Watcher reports when the system resumes from suspend or its network
+// connections change
+type Watcher struct {
+	cfg WatcherCfg
+
+	cEvents chan Event
+}
+
+func NewWatcher(cfg WatcherCfg) *Watcher {
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{start: time.Now()}
+	}
+
+	return &Watcher{
+		cfg:     cfg,
+		cEvents: make(chan Event, 8),
+	}
+}
+
+// Events receives an event for every resume and network change. events are
+// dropped if they are not received, and often come in bursts
+func (w *Watcher) Events() <-chan Event {
+	return w.cEvents
+}
+
+func (w *Watcher) Watch(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		w.watchClock(ctx)
+		return nil
+	})
+
+	g.Go(func() error {
+		watchPlatform(ctx, w.emit)
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// watchClock detects resumes on every platform. the monotonic clock stops
+// while the system is suspended but the wall clock does not, so a suspend shows
+// up as the wall clock jumping ahead
+func (w *Watcher) watchClock(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.ClockCheckFreq)
+	defer ticker.Stop()
+
+	lastWall, lastMonotonic := w.cfg.Clock.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		nowWall, nowMonotonic := w.cfg.Clock.Now()
+		wall := nowWall.Sub(lastWall)
+		monotonic := nowMonotonic - lastMonotonic
+		lastWall, lastMonotonic = nowWall, nowMonotonic
+
+		if wall-monotonic > w.cfg.ClockJumpThreshold {
+			slog.Debug("wall clock jumped ahead of monotonic clock", "wall", wall, "monotonic", monotonic)
+			w.emit(Event_Resume)
+		}
+	}
+}
+
+func (w *Watcher) emit(e Event) {
+	select {
+	case w.cEvents <- e:
+	default:
+	}
+}
@@ -0,0 +1,132 @@
+//go:build linux
+
+package sysevents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+	"golang.org/x/sys/unix"
+)
+
+// watchPlatform listens for logind sleep signals and netlink route changes.
+// either is skipped if it is not available
+func watchPlatform(ctx context.Context, emit func(Event)) {
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		err := watchLogind(ctx, emit)
+		if err != nil {
+			slog.Warn("not watching logind for resume", "error", err)
+		}
+	})
+
+	wg.Go(func() {
+		err := watchNetlink(ctx, emit)
+		if err != nil {
+			slog.Warn("not watching netlink for network changes", "error", err)
+		}
+	})
+
+	wg.Wait()
+}
+
+// watchLogind emits a resume when logind signals PrepareForSleep(false), which
+// it sends after the system wakes up
+func watchLogind(ctx context.Context, emit func(Event)) error {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return fmt.Errorf("error while connecting to system bus: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath("/org/freedesktop/login1"),
+		dbus.WithMatchInterface("org.freedesktop.login1.Manager"),
+		dbus.WithMatchMember("PrepareForSleep"),
+	)
+	if err != nil {
+		return fmt.Errorf("error while subscribing to PrepareForSleep: %v", err)
+	}
+
+	signals := make(chan *dbus.Signal, 8)
+	conn.Signal(signals)
+
+	for {
+		select {
+		case sig, ok := <-signals:
+			if !ok {
+				return errors.New("system bus connection closed")
+			}
+
+			if len(sig.Body) == 0 {
+				continue
+			}
+
+			if sleeping, ok := sig.Body[0].(bool); ok && !sleeping {
+				slog.Debug("logind reported resume")
+				emit(Event_Resume)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// watchNetlink emits a network change whenever an address or route is added,
+// which happens when a connection comes up
+func watchNetlink(ctx context.Context, emit func(Event)) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("error while opening netlink socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	err = unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR | unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE,
+	})
+	if err != nil {
+		return fmt.Errorf("error while binding netlink socket: %v", err)
+	}
+
+	// closing the socket does not interrupt a blocked read, so wake up
+	// regularly to check whether to stop
+	err = unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &unix.Timeval{Sec: 1})
+	if err != nil {
+		return fmt.Errorf("error while setting netlink socket timeout: %v", err)
+	}
+
+	buf := make([]byte, unix.Getpagesize())
+	for ctx.Err() == nil {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("error while reading netlink socket: %v", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			slog.Debug("failed to parse netlink message", "error", err)
+			continue
+		}
+
+		for _, m := range msgs {
+			if m.Header.Type == unix.RTM_NEWADDR || m.Header.Type == unix.RTM_NEWROUTE {
+				slog.Debug("netlink reported network change", "type", m.Header.Type)
+				emit(Event_NetworkChanged)
+				break
+			}
+		}
+	}
+
+	return nil
+}
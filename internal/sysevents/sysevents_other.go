@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sysevents
+
+import "context"
+
+// watchPlatform does nothing on this platform. resumes are still detected from
+// the clock, network changes are not
+func watchPlatform(ctx context.Context, emit func(Event)) {}
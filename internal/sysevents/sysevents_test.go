@@ -0,0 +1,97 @@
+package sysevents_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/sysevents"
+)
+
+// fakeClock advances both clocks by step on every read. a jump is added to the
+// wall clock only, like a suspend does
+type fakeClock struct {
+	mu        sync.Mutex
+	step      time.Duration
+	wall      time.Time
+	monotonic time.Duration
+	jump      time.Duration
+	reads     int
+}
+
+func (c *fakeClock) Now() (time.Time, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.wall = c.wall.Add(c.step + c.jump)
+	c.monotonic += c.step
+	c.jump = 0
+	c.reads++
+
+	return c.wall, c.monotonic
+}
+
+func (c *fakeClock) Jump(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.jump = d
+}
+
+func (c *fakeClock) Reads() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reads
+}
+
+func TestWatcherClockJump(t *testing.T) {
+	clock := &fakeClock{step: time.Second * 5, wall: time.Now()}
+
+	w := sysevents.NewWatcher(sysevents.WatcherCfg{
+		ClockCheckFreq:     time.Millisecond,
+		ClockJumpThreshold: time.Second * 10,
+		Clock:              clock,
+	})
+
+	go w.Watch(t.Context())
+
+	// waits for the clock to be checked n more times and returns the resumes
+	// that were emitted in the meantime. network changes of the machine
+	// running the test are ignored
+	resumesAfter := func(n int) int {
+		until := clock.Reads() + n
+		deadline := time.Now().Add(time.Second * 5)
+
+		resumes := 0
+		for clock.Reads() < until {
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for the clock to be checked")
+			}
+
+			select {
+			case e := <-w.Events():
+				if e == sysevents.Event_Resume {
+					resumes++
+				}
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		return resumes
+	}
+
+	if n := resumesAfter(20); n != 0 {
+		t.Fatalf("expected normal ticks to not emit a resume, got %d", n)
+	}
+
+	clock.Jump(time.Hour)
+
+	if n := resumesAfter(20); n != 1 {
+		t.Fatalf("expected a jump of the wall clock to emit one resume, got %d", n)
+	}
+
+	if n := resumesAfter(20); n != 0 {
+		t.Fatalf("expected normal ticks after the jump to not emit a resume, got %d", n)
+	}
+}
@@ -15,3 +15,32 @@ func ShowNotification(title, message string) {
 func ShowNotificationWithIcon(title, message string, icon []byte) {
 	beeep.Notify(title, message, icon)
 }
+
+type Urgency int
+
+const (
+	Urgency_Normal Urgency = iota
+	Urgency_Low
+	Urgency_Critical
+)
+
+type Action struct {
+	Key   string
+	Label string
+}
+
+type Notification struct {
+	Title   string
+	Message string
+
+	// Urgency_Critical notifications stay on screen until they are dismissed
+	// on most notification servers
+	Urgency Urgency
+
+	// Actions are shown as buttons on the notification where the
+	// notification server supports them
+	Actions []Action
+
+	// OnAction is called with the key of the action picked by the user
+	OnAction func(key string)
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package sysnotif
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/esiqveland/notify"
+	"github.com/gen2brain/beeep"
+	"github.com/godbus/dbus/v5"
+)
+
+const closedGracePeriod = time.Second * 5
+
+var (
+	notifierOnce sync.Once
+	notifier     notify.Notifier
+
+	onActionMu sync.Mutex
+	onAction   = make(map[uint32]func(key string))
+)
+
+// Show displays n. if the desktop notification service cannot be reached over
+// d-bus it falls back to a plain notification without actions
+func Show(n *Notification) {
+	notifierOnce.Do(connectNotifier)
+
+	if notifier == nil {
+		showFallback(n)
+		return
+	}
+
+	note := notify.Notification{
+		AppName:       beeep.AppName,
+		Summary:       n.Title,
+		Body:          n.Message,
+		ExpireTimeout: notify.ExpireTimeoutSetByNotificationServer,
+	}
+
+	switch n.Urgency {
+	case Urgency_Low:
+		note.SetUrgency(notify.UrgencyLow)
+	case Urgency_Critical:
+		note.SetUrgency(notify.UrgencyCritical)
+	default:
+		note.SetUrgency(notify.UrgencyNormal)
+	}
+
+	for _, a := range n.Actions {
+		note.Actions = append(note.Actions, notify.Action{Key: a.Key, Label: a.Label})
+	}
+
+	// hold the lock while sending so that an action invoked straight away
+	// cannot be handled before the callback is registered
+	onActionMu.Lock()
+	defer onActionMu.Unlock()
+
+	id, err := notifier.SendNotification(note)
+	if err != nil {
+		slog.Error("failed to send notification over d-bus", "error", err)
+		showFallback(n)
+		return
+	}
+
+	if n.OnAction != nil {
+		onAction[id] = n.OnAction
+	}
+}
+
+func showFallback(n *Notification) {
+	if n.Urgency == Urgency_Critical {
+		beeep.Alert(n.Title, n.Message, "")
+	} else {
+		beeep.Notify(n.Title, n.Message, "")
+	}
+}
+
+func connectNotifier() {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		slog.Warn("failed to connect to d-bus session bus, notification actions are unavailable", "error", err)
+		return
+	}
+
+	n, err := notify.New(conn, notify.WithOnAction(handleAction), notify.WithOnClosed(handleClosed))
+	if err != nil {
+		slog.Warn("failed to create d-bus notifier, notification actions are unavailable", "error", err)
+		conn.Close()
+		return
+	}
+
+	notifier = n
+}
+
+func handleAction(s *notify.ActionInvokedSignal) {
+	onActionMu.Lock()
+	f, ok := onAction[s.ID]
+	delete(onAction, s.ID)
+	onActionMu.Unlock()
+
+	if ok {
+		go f(s.ActionKey)
+	}
+}
+
+func handleClosed(s *notify.NotificationClosedSignal) {
+	// some notification servers send the closed signal before the action
+	// signal, so wait a moment before forgetting the callback
+	time.AfterFunc(closedGracePeriod, func() {
+		onActionMu.Lock()
+		defer onActionMu.Unlock()
+
+		delete(onAction, s.ID)
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package sysnotif
+
+import "github.com/gen2brain/beeep"
+
+// Show displays n. actions are not supported on this platform, so only the
+// title and message are shown
+func Show(n *Notification) {
+	if n.Urgency == Urgency_Critical {
+		beeep.Alert(n.Title, n.Message, "")
+	} else {
+		beeep.Notify(n.Title, n.Message, "")
+	}
+}
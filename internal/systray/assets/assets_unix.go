@@ -6,3 +6,6 @@ import _ "embed"
 
 //go:embed tray.png
 var TrayIcon []byte
+
+//go:embed tray_offline.png
+var TrayIconOffline []byte
@@ -6,3 +6,6 @@ import _ "embed"
 
 //go:embed tray.ico
 var TrayIcon []byte
+
+//go:embed tray_offline.ico
+var TrayIconOffline []byte
@@ -2,26 +2,87 @@ package systray
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/getlantern/systray"
+	"github.com/link00000000/gwsn/internal/gworkspace"
 	"github.com/link00000000/gwsn/internal/systray/assets"
 	"golang.org/x/sync/errgroup"
 )
 
+// number of recent messages listed in the menu. the menu cannot grow or
+// shrink once created, so unused slots are hidden
+const numRecentMessageSlots = 5
+
+// actions offered for each recent message. actions that would not change the
+// message are hidden
+var recentMessageActions = []gworkspace.GmailAction{
+	gworkspace.GmailAction_MarkRead,
+	gworkspace.GmailAction_MarkUnread,
+	gworkspace.GmailAction_Archive,
+	gworkspace.GmailAction_Star,
+	gworkspace.GmailAction_Unstar,
+	gworkspace.GmailAction_Trash,
+	gworkspace.GmailAction_Mute,
+}
+
+const defaultTitle = "Google Workspace Notify"
+
 var running atomic.Bool
 
 func init() {
 	running.Store(false)
 }
 
+type RecentMessage struct {
+	Id     uint64
+	Title  string
+	Labels []string
+}
+
+type MessageActionReq struct {
+	Id     uint64
+	Action gworkspace.GmailAction
+}
+
+type SnoozeReq struct {
+	Id     uint64
+	Option gworkspace.GmailSnoozeOption
+}
+
+type recentMessageSlot struct {
+	item    *systray.MenuItem
+	actions map[gworkspace.GmailAction]*systray.MenuItem
+}
+
 type Systray struct {
 	g      *errgroup.Group
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	cExitReq chan struct{}
+	mu             sync.Mutex
+	ready          bool
+	offline        bool
+	dndReason      string
+	title          string
+	tooltip        string
+	nextMeeting    *gworkspace.CalendarEvent
+	mNext          *systray.MenuItem
+	mNextJoin      *systray.MenuItem
+	mRecent        *systray.MenuItem
+	recentSlots    []*recentMessageSlot
+	recentMessages []RecentMessage
+
+	cExitReq          chan struct{}
+	cMessageActionReq chan MessageActionReq
+	cSnoozeReq        chan SnoozeReq
+	cJoinReq          chan string
 }
 
 func NewSystray() *Systray {
@@ -29,10 +90,14 @@ func NewSystray() *Systray {
 	g, ctx := errgroup.WithContext(ctx)
 
 	return &Systray{
-		g:        g,
-		ctx:      ctx,
-		cancel:   cancel,
-		cExitReq: make(chan struct{}),
+		g:                 g,
+		ctx:               ctx,
+		cancel:            cancel,
+		title:             defaultTitle,
+		cExitReq:          make(chan struct{}),
+		cMessageActionReq: make(chan MessageActionReq),
+		cSnoozeReq:        make(chan SnoozeReq),
+		cJoinReq:          make(chan string),
 	}
 }
 
@@ -43,8 +108,16 @@ func (s *Systray) Start() error {
 
 	s.g.Go(func() error {
 		systray.Run(func() {
-			systray.SetIcon(assets.TrayIcon)
-			systray.SetTitle("Google Workspace Notify")
+			s.mu.Lock()
+			s.ready = true
+			s.updateIcon()
+			s.updateTitle()
+			s.mu.Unlock()
+
+			s.addNextMeetingMenu()
+			s.addRecentMessagesMenu()
+
+			systray.AddSeparator()
 
 			mSettings := systray.AddMenuItem("Settings", "")
 			s.g.Go(func() error { return s.runSystrayClickHandlerSettings(mSettings) })
@@ -79,6 +152,320 @@ func (s *Systray) ExitReq() <-chan struct{} {
 	return s.cExitReq
 }
 
+func (s *Systray) MessageActionReq() <-chan MessageActionReq {
+	return s.cMessageActionReq
+}
+
+func (s *Systray) SnoozeReq() <-chan SnoozeReq {
+	return s.cSnoozeReq
+}
+
+// JoinReq receives the join link of the next meeting when the user asks to
+// join it
+func (s *Systray) JoinReq() <-chan string {
+	return s.cJoinReq
+}
+
+// SetNextMeeting shows the meeting that is in progress or starts next. the
+// entry is hidden if ev is nil
+func (s *Systray) SetNextMeeting(ev *gworkspace.CalendarEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextMeeting = ev
+	s.updateNextMeetingMenu()
+}
+
+// SetRecentMessages replaces the messages listed in the recent messages menu.
+// only the first few messages are shown
+func (s *Systray) SetRecentMessages(msgs []RecentMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recentMessages = msgs
+	s.updateRecentMessagesMenu()
+}
+
+// SetUnreadCounts shows the total number of unread messages in the title and
+// the count for each label in the tooltip
+func (s *Systray) SetUnreadCounts(counts []gworkspace.GmailLabelCounts) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total int64
+	lines := make([]string, 0, len(counts))
+	for _, c := range counts {
+		total += c.MessagesUnread
+		lines = append(lines, fmt.Sprintf("%s: %d unread (%d threads)", c.Name, c.MessagesUnread, c.ThreadsUnread))
+	}
+
+	s.title = defaultTitle
+	if total > 0 {
+		s.title = fmt.Sprintf("%s (%d)", defaultTitle, total)
+	}
+
+	s.tooltip = strings.Join(lines, "\n")
+	s.updateTitle()
+}
+
+// SetOffline switches to the offline icon and notes in the tooltip that the
+// counts may be out of date
+func (s *Systray) SetOffline(offline bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.offline == offline {
+		return
+	}
+
+	s.offline = offline
+	s.updateIcon()
+	s.updateTitle()
+}
+
+// SetDoNotDisturb shows why notifications are being held back in the tooltip,
+// or removes it if reason is empty
+func (s *Systray) SetDoNotDisturb(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dndReason == reason {
+		return
+	}
+
+	s.dndReason = reason
+	s.updateTitle()
+}
+
+// updateIcon must be called with s.mu held
+func (s *Systray) updateIcon() {
+	if !s.ready {
+		return
+	}
+
+	if s.offline {
+		systray.SetIcon(assets.TrayIconOffline)
+	} else {
+		systray.SetIcon(assets.TrayIcon)
+	}
+}
+
+// updateTitle must be called with s.mu held
+func (s *Systray) updateTitle() {
+	if !s.ready {
+		return
+	}
+
+	tooltip := s.tooltip
+	if s.dndReason != "" {
+		tooltip = strings.TrimSpace("Do not disturb (" + s.dndReason + ")\n" + tooltip)
+	}
+
+	if s.offline {
+		tooltip = strings.TrimSpace("Offline\n" + tooltip)
+	}
+
+	systray.SetTitle(s.title)
+	systray.SetTooltip(tooltip)
+}
+
+func (s *Systray) addNextMeetingMenu() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mNext = systray.AddMenuItem("", "")
+	s.mNextJoin = s.mNext.AddSubMenuItem("Join", "")
+	s.g.Go(func() error { return s.runSystrayClickHandlerJoin(s.mNextJoin) })
+
+	s.updateNextMeetingMenu()
+}
+
+// updateNextMeetingMenu must be called with s.mu held
+func (s *Systray) updateNextMeetingMenu() {
+	if s.mNext == nil {
+		return
+	}
+
+	ev := s.nextMeeting
+	if ev == nil {
+		s.mNext.Hide()
+		return
+	}
+
+	summary := ev.Summary
+	if summary == "" {
+		summary = "(No title)"
+	}
+
+	start, end := ev.Start.Local(), ev.End.Local()
+	layout := "15:04"
+	if now := time.Now(); start.YearDay() != now.YearDay() || start.Year() != now.Year() {
+		layout = "Mon 15:04"
+	}
+
+	s.mNext.SetTitle(fmt.Sprintf("Next: %s · %s–%s", summary, start.Format(layout), end.Format("15:04")))
+	s.mNext.Show()
+
+	if ev.ConferenceLink != "" {
+		s.mNextJoin.Show()
+	} else {
+		s.mNextJoin.Hide()
+	}
+}
+
+func (s *Systray) addRecentMessagesMenu() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mRecent = systray.AddMenuItem("Recent messages", "")
+
+	for range numRecentMessageSlots {
+		slot := &recentMessageSlot{
+			item:    s.mRecent.AddSubMenuItem("", ""),
+			actions: make(map[gworkspace.GmailAction]*systray.MenuItem, len(recentMessageActions)),
+		}
+
+		for _, action := range recentMessageActions {
+			m := slot.item.AddSubMenuItem(action.Title(), "")
+			slot.actions[action] = m
+			s.g.Go(func() error { return s.runSystrayClickHandlerMessageAction(m, slot, action) })
+		}
+
+		for _, option := range gworkspace.AllGmailSnoozeOptions {
+			m := slot.item.AddSubMenuItem(option.Title(), "")
+			s.g.Go(func() error { return s.runSystrayClickHandlerSnooze(m, slot, option) })
+		}
+
+		s.recentSlots = append(s.recentSlots, slot)
+	}
+
+	s.updateRecentMessagesMenu()
+}
+
+// updateRecentMessagesMenu must be called with s.mu held
+func (s *Systray) updateRecentMessagesMenu() {
+	if s.mRecent == nil {
+		// menu has not been created yet. it will be updated once it is
+		return
+	}
+
+	if len(s.recentMessages) == 0 {
+		s.mRecent.Disable()
+	} else {
+		s.mRecent.Enable()
+	}
+
+	for i, slot := range s.recentSlots {
+		if i >= len(s.recentMessages) {
+			slot.item.Hide()
+			continue
+		}
+
+		msg := s.recentMessages[i]
+
+		title := msg.Title
+		if slices.Contains(msg.Labels, gworkspace.GmailLabel_Unread) {
+			title = "• " + title
+		}
+
+		slot.item.SetTitle(title)
+		slot.item.Show()
+
+		for action, m := range slot.actions {
+			if action.AppliesTo(msg.Labels) {
+				m.Show()
+			} else {
+				m.Hide()
+			}
+		}
+	}
+}
+
+func (s *Systray) runSystrayClickHandlerMessageAction(m *systray.MenuItem, slot *recentMessageSlot, action gworkspace.GmailAction) error {
+	for {
+		select {
+		case <-m.ClickedCh:
+			id, ok := s.slotMessageId(slot)
+			if !ok {
+				continue
+			}
+
+			log.Println("message action systray menu item clicked")
+
+			select {
+			case s.cMessageActionReq <- MessageActionReq{Id: id, Action: action}:
+			case <-s.ctx.Done():
+				return nil
+			}
+		case <-s.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *Systray) runSystrayClickHandlerSnooze(m *systray.MenuItem, slot *recentMessageSlot, option gworkspace.GmailSnoozeOption) error {
+	for {
+		select {
+		case <-m.ClickedCh:
+			id, ok := s.slotMessageId(slot)
+			if !ok {
+				continue
+			}
+
+			log.Println("snooze systray menu item clicked")
+
+			select {
+			case s.cSnoozeReq <- SnoozeReq{Id: id, Option: option}:
+			case <-s.ctx.Done():
+				return nil
+			}
+		case <-s.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// slotMessageId returns the id of the message currently shown in the slot
+func (s *Systray) slotMessageId(slot *recentMessageSlot) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.Index(s.recentSlots, slot)
+	if i < 0 || i >= len(s.recentMessages) {
+		return 0, false
+	}
+
+	return s.recentMessages[i].Id, true
+}
+
+func (s *Systray) runSystrayClickHandlerJoin(m *systray.MenuItem) error {
+	for {
+		select {
+		case <-m.ClickedCh:
+			s.mu.Lock()
+			var link string
+			if s.nextMeeting != nil {
+				link = s.nextMeeting.ConferenceLink
+			}
+			s.mu.Unlock()
+
+			if link == "" {
+				continue
+			}
+
+			log.Println("join systray menu item clicked")
+
+			select {
+			case s.cJoinReq <- link:
+			case <-s.ctx.Done():
+				return nil
+			}
+		case <-s.ctx.Done():
+			return nil
+		}
+	}
+}
+
 func (s *Systray) runSystrayClickHandlerSettings(m *systray.MenuItem) error {
 	for {
 		select {
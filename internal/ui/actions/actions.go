@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/ui/web"
+)
+
+// NewHandler applies a gmail action to the message of a history entry. the
+// index page calls it with fetch and expects json, plain form submissions are
+// redirected back to the index page. requests need a csrf token from the
+// index page
+func NewHandler(hist *history.History, acts *gworkspace.GmailActions, tokens *web.Tokens) http.HandlerFunc {
+	return web.Guard(tokens, func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+		if err != nil {
+			web.WriteResponse(w, r, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		action, err := gworkspace.ParseGmailAction(r.FormValue("action"))
+		if err != nil {
+			web.WriteResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		err = hist.ApplyGmailAction(r.Context(), acts, id, action)
+		if err != nil {
+			slog.Error("failed to apply gmail action from web ui", "id", id, "action", action, "error", err)
+			web.WriteResponse(w, r, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		web.WriteResponse(w, r, http.StatusOK, "")
+	})
+}
@@ -0,0 +1,41 @@
+package agenda
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+)
+
+//go:embed agenda.html
+var f embed.FS
+
+type viewModel struct {
+	Agenda   *gworkspace.CalendarAgenda
+	Location *time.Location
+}
+
+// NewHandler serves the agenda of the rest of today. cal is nil if the
+// calendar is disabled
+func NewHandler(cal *gworkspace.CalendarMonitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cal == nil {
+			http.Error(w, "calendar is disabled", http.StatusNotFound)
+			return
+		}
+
+		loc := cal.Location()
+		tmpl := template.Must(template.New("agenda.html").Funcs(template.FuncMap{
+			"clock": func(t time.Time) string { return t.In(loc).Format("15:04") },
+		}).ParseFS(f, "agenda.html"))
+
+		vm := viewModel{
+			Agenda:   cal.Agenda(time.Now()),
+			Location: loc,
+		}
+
+		tmpl.Execute(w, vm)
+	}
+}
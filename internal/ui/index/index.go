@@ -4,16 +4,55 @@ import (
 	"embed"
 	"html/template"
 	"net/http"
+	"strings"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/status"
+	"github.com/link00000000/gwsn/internal/ui/web"
 )
 
 //go:embed index.html
 var f embed.FS
 
+type labelChanges struct {
+	Add    []string
+	Remove []string
+}
+
 type viewModel struct {
+	UnreadCounts []gworkspace.GmailLabelCounts
+
+	Entries []history.Entry
+	Actions []gworkspace.GmailAction
+	Rsvps   []gworkspace.CalendarRsvp
+
+	// LabelChanges lets the page update a message optimistically before the
+	// server has responded
+	LabelChanges map[gworkspace.GmailAction]labelChanges
+
+	// Csrf is sent with the forms of the page
+	Csrf string
 }
 
-func Handle(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(f, "index.html"))
-	vm := viewModel{}
-	tmpl.Execute(w, vm)
+func NewHandler(hist *history.History, st *status.Status, tokens *web.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl := template.Must(template.New("index.html").Funcs(template.FuncMap{"join": strings.Join}).ParseFS(f, "index.html"))
+
+		vm := viewModel{
+			UnreadCounts: st.UnreadCounts(),
+			Entries:      hist.Entries(),
+			Actions:      gworkspace.AllGmailActions,
+			Rsvps:        gworkspace.AllCalendarRsvps,
+			LabelChanges: make(map[gworkspace.GmailAction]labelChanges, len(gworkspace.AllGmailActions)),
+			Csrf:         tokens.Mint(),
+		}
+
+		for _, action := range gworkspace.AllGmailActions {
+			add, remove := action.LabelChanges()
+			vm.LabelChanges[action] = labelChanges{Add: add, Remove: remove}
+		}
+
+		tmpl.Execute(w, vm)
+	}
 }
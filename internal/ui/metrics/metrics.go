@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/link00000000/gwsn/internal/status"
+)
+
+// NewHandler serves the status in the prometheus text format
+func NewHandler(st *status.Status) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		online := 1
+		if st.Offline() {
+			online = 0
+		}
+
+		fmt.Fprintln(w, "# HELP gwsn_gmail_online Whether gmail can be reached.")
+		fmt.Fprintln(w, "# TYPE gwsn_gmail_online gauge")
+		fmt.Fprintf(w, "gwsn_gmail_online %d\n", online)
+
+		fmt.Fprintln(w, "# HELP gwsn_gmail_unread_messages Unread messages in a watched label.")
+		fmt.Fprintln(w, "# TYPE gwsn_gmail_unread_messages gauge")
+		for _, c := range st.UnreadCounts() {
+			fmt.Fprintf(w, "gwsn_gmail_unread_messages{label=%q} %d\n", c.LabelId, c.MessagesUnread)
+		}
+
+		usage := st.QuotaUsage()
+
+		fmt.Fprintln(w, "# HELP gwsn_gmail_quota_window_used_units Gmail quota units used in a rolling window.")
+		fmt.Fprintln(w, "# TYPE gwsn_gmail_quota_window_used_units gauge")
+		for _, u := range usage.Windows {
+			fmt.Fprintf(w, "gwsn_gmail_quota_window_used_units{window=%q} %d\n", u.Duration, u.Used)
+		}
+
+		fmt.Fprintln(w, "# HELP gwsn_gmail_quota_window_budget_units Gmail quota budget of a rolling window.")
+		fmt.Fprintln(w, "# TYPE gwsn_gmail_quota_window_budget_units gauge")
+		for _, u := range usage.Windows {
+			fmt.Fprintf(w, "gwsn_gmail_quota_window_budget_units{window=%q} %d\n", u.Duration, u.Budget)
+		}
+
+		fmt.Fprintln(w, "# HELP gwsn_gmail_requests_total Gmail api requests by method.")
+		fmt.Fprintln(w, "# TYPE gwsn_gmail_requests_total counter")
+		for _, m := range usage.Methods {
+			fmt.Fprintf(w, "gwsn_gmail_requests_total{method=%q} %d\n", m.Method, m.Calls)
+		}
+
+		fmt.Fprintln(w, "# HELP gwsn_gmail_quota_units_total Gmail quota units used by method.")
+		fmt.Fprintln(w, "# TYPE gwsn_gmail_quota_units_total counter")
+		for _, m := range usage.Methods {
+			fmt.Fprintf(w, "gwsn_gmail_quota_units_total{method=%q} %d\n", m.Method, m.Units)
+		}
+
+		rateLimited := 0
+		if time.Now().Before(usage.BackoffUntil) {
+			rateLimited = 1
+		}
+
+		fmt.Fprintln(w, "# HELP gwsn_gmail_rate_limited Whether gmail requests are held back after being rate limited.")
+		fmt.Fprintln(w, "# TYPE gwsn_gmail_rate_limited gauge")
+		fmt.Fprintf(w, "gwsn_gmail_rate_limited %d\n", rateLimited)
+	}
+}
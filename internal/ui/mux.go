@@ -3,15 +3,33 @@ package ui
 import (
 	"net/http"
 
+	"github.com/link00000000/gwsn/internal/config"
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/status"
+	ui_actions "github.com/link00000000/gwsn/internal/ui/actions"
+	ui_agenda "github.com/link00000000/gwsn/internal/ui/agenda"
 	ui_index "github.com/link00000000/gwsn/internal/ui/index"
+	ui_metrics "github.com/link00000000/gwsn/internal/ui/metrics"
+	ui_reply "github.com/link00000000/gwsn/internal/ui/reply"
+	ui_rsvp "github.com/link00000000/gwsn/internal/ui/rsvp"
 	ui_settings "github.com/link00000000/gwsn/internal/ui/settings"
+	"github.com/link00000000/gwsn/internal/ui/web"
 )
 
-func NewHandler() http.Handler {
+func NewHandler(cfg *config.Config, hist *history.History, st *status.Status, acts *gworkspace.GmailActions, cal *gworkspace.CalendarMonitor) http.Handler {
 	m := http.NewServeMux()
 
-	m.HandleFunc("/", ui_index.Handle)
-	m.HandleFunc("/settings", ui_settings.Handle)
+	// pages mint csrf tokens for the forms that change something
+	tokens := web.NewTokens()
+
+	m.HandleFunc("/", ui_index.NewHandler(hist, st, tokens))
+	m.HandleFunc("/settings", ui_settings.NewHandler(cfg, cal, tokens))
+	m.HandleFunc("POST /gmail/action", ui_actions.NewHandler(hist, acts, tokens))
+	m.HandleFunc("/agenda", ui_agenda.NewHandler(cal))
+	m.HandleFunc("POST /calendar/rsvp", ui_rsvp.NewHandler(hist, cal, tokens))
+	m.HandleFunc("/reply", ui_reply.NewHandler(hist, acts, tokens))
+	m.HandleFunc("/metrics", ui_metrics.NewHandler(st))
 
 	return m
 }
@@ -0,0 +1,114 @@
+package reply
+
+import (
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/ui/web"
+)
+
+//go:embed reply.html
+var f embed.FS
+
+type step string
+
+const (
+	step_Compose step = "compose"
+	step_Confirm step = "confirm"
+	step_Sent    step = "sent"
+)
+
+type viewModel struct {
+	Step  step
+	Entry history.Entry
+	Reply *gworkspace.GmailReply
+	Body  string
+	Error string
+
+	// Csrf is minted for the confirm step and is needed to send the reply
+	Csrf string
+}
+
+// NewHandler serves a form for replying to the message of a history entry.
+// the reply is shown for confirmation before it is sent, and only a confirm
+// step rendered by this server can send it
+func NewHandler(hist *history.History, acts *gworkspace.GmailActions, tokens *web.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl := template.Must(template.ParseFS(f, "reply.html"))
+
+		if r.Method == http.MethodPost && !web.SameOrigin(r) {
+			http.Error(w, "cross-origin request", http.StatusForbidden)
+			return
+		}
+
+		id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+
+		e, ok := hist.Get(id)
+		if !ok || e.Message == nil {
+			http.Error(w, "message not found", http.StatusNotFound)
+			return
+		}
+
+		vm := viewModel{
+			Step:  step_Compose,
+			Entry: e,
+			Body:  r.FormValue("body"),
+		}
+
+		if r.Method != http.MethodPost || r.FormValue("step") == "edit" {
+			tmpl.Execute(w, vm)
+			return
+		}
+
+		if strings.TrimSpace(vm.Body) == "" {
+			vm.Error = "Reply cannot be empty"
+			tmpl.Execute(w, vm)
+			return
+		}
+
+		vm.Reply, err = acts.PrepareReply(r.Context(), e.Message)
+		if err != nil {
+			slog.Error("failed to prepare reply", "id", id, "error", err)
+			vm.Error = err.Error()
+			tmpl.Execute(w, vm)
+			return
+		}
+
+		vm.Reply.Body = vm.Body
+		vm.Step = step_Confirm
+		vm.Csrf = tokens.Mint()
+
+		if r.FormValue("step") != "send" {
+			tmpl.Execute(w, vm)
+			return
+		}
+
+		// each confirm step can send once
+		if !tokens.Consume(r.FormValue(web.CsrfField)) {
+			vm.Error = "This confirmation has expired or was already sent, review the reply again"
+			tmpl.Execute(w, vm)
+			return
+		}
+
+		err = acts.SendReply(r.Context(), vm.Reply)
+		if err != nil {
+			slog.Error("failed to send reply", "id", id, "error", err)
+			vm.Error = err.Error()
+			tmpl.Execute(w, vm)
+			return
+		}
+
+		vm.Step = step_Sent
+		tmpl.Execute(w, vm)
+	}
+}
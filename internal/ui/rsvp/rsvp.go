@@ -0,0 +1,45 @@
+package rsvp
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/ui/web"
+)
+
+// NewHandler responds to the invitation to the calendar event of a history
+// entry. like the gmail actions, fetch requests get json and plain form
+// submissions are redirected back to the index page. requests need a csrf
+// token from the index page. cal is nil if the calendar is disabled
+func NewHandler(hist *history.History, cal *gworkspace.CalendarMonitor, tokens *web.Tokens) http.HandlerFunc {
+	return web.Guard(tokens, func(w http.ResponseWriter, r *http.Request) {
+		if cal == nil {
+			web.WriteResponse(w, r, http.StatusNotFound, "calendar is disabled")
+			return
+		}
+
+		id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+		if err != nil {
+			web.WriteResponse(w, r, http.StatusBadRequest, "invalid id")
+			return
+		}
+
+		rsvp, err := gworkspace.ParseCalendarRsvp(r.FormValue("response"))
+		if err != nil {
+			web.WriteResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		err = hist.RespondToEvent(r.Context(), cal, id, rsvp, r.FormValue("comment"))
+		if err != nil {
+			slog.Error("failed to respond to calendar event from web ui", "id", id, "response", rsvp, "error", err)
+			web.WriteResponse(w, r, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		web.WriteResponse(w, r, http.StatusOK, "")
+	})
+}
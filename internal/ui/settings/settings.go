@@ -3,17 +3,156 @@ package settings
 import (
 	"embed"
 	"html/template"
+	"log/slog"
 	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/link00000000/gwsn/internal/config"
+	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/ui/web"
 )
 
 //go:embed settings.html
 var f embed.FS
 
+// primaryCalendarId is how the user's own calendar is kept in the config, so
+// that it does not depend on the address of the account
+const primaryCalendarId = "primary"
+
+type calendarRow struct {
+	Id string
+
+	// OtherId is the address of the primary calendar, which it may be
+	// configured by instead of primaryCalendarId
+	OtherId string
+
+	Name          string
+	AccessRole    string
+	Remind        bool
+	NotifyChanges bool
+}
+
 type viewModel struct {
+	CalendarEnabled bool
+	Calendars       []calendarRow
+	Saved           bool
+	Error           string
+	Csrf            string
+}
+
+// NewHandler serves the settings page. saving writes the config file and
+// applies the change straight away, and needs a csrf token from the page. cal
+// is nil if the calendar is disabled
+func NewHandler(cfg *config.Config, cal *gworkspace.CalendarMonitor, tokens *web.Tokens) http.HandlerFunc {
+	// guards cfg against concurrent saves
+	var mu sync.Mutex
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		tmpl := template.Must(template.ParseFS(f, "settings.html"))
+
+		vm := viewModel{CalendarEnabled: cal != nil}
+		if cal == nil {
+			tmpl.Execute(w, vm)
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == http.MethodPost {
+			if !web.SameOrigin(r) || !tokens.Valid(r.FormValue(web.CsrfField)) {
+				http.Error(w, "invalid or expired csrf token, reload the page", http.StatusForbidden)
+				return
+			}
+
+			err := saveCalendars(r, cfg, cal)
+			if err != nil {
+				slog.Error("failed to save calendar settings", "error", err)
+				vm.Error = err.Error()
+			} else {
+				vm.Saved = true
+			}
+		}
+
+		entries, err := cal.ListCalendars(r.Context())
+		if err != nil {
+			slog.Error("failed to list calendars for settings", "error", err)
+			vm.Error = err.Error()
+		}
+
+		for _, e := range entries {
+			id := e.Id
+			if e.Primary {
+				id = primaryCalendarId
+			}
+
+			row := calendarRow{Id: id, Name: e.Name, AccessRole: e.AccessRole}
+			if e.Primary {
+				row.OtherId = e.Id
+			}
+
+			i := slices.IndexFunc(cfg.Calendar.Calendars, func(c config.CalendarSelectionConfig) bool {
+				return c.Id == id || (e.Primary && c.Id == e.Id)
+			})
+			if i >= 0 {
+				row.Remind = cfg.Calendar.Calendars[i].Remind
+				row.NotifyChanges = cfg.Calendar.Calendars[i].NotifyChanges
+			}
+
+			vm.Calendars = append(vm.Calendars, row)
+		}
+
+		vm.Csrf = tokens.Mint()
+
+		tmpl.Execute(w, vm)
+	}
 }
 
-func Handle(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFS(f, "settings.html"))
-	vm := viewModel{}
-	tmpl.Execute(w, vm)
+// saveCalendars selects the calendars posted by the form. calendars with
+// neither reminders nor changes are ignored. configured calendars that were not
+// in the form, e.g. because they could not be listed, are kept as they are.
+// must be called with mu held
+func saveCalendars(r *http.Request, cfg *config.Config, cal *gworkspace.CalendarMonitor) error {
+	err := r.ParseForm()
+	if err != nil {
+		return err
+	}
+
+	shown := slices.Concat(r.PostForm["calendar"], r.PostForm["otherId"])
+
+	selected := make([]config.CalendarSelectionConfig, 0)
+	for _, c := range cfg.Calendar.Calendars {
+		if !slices.Contains(shown, c.Id) {
+			selected = append(selected, c)
+		}
+	}
+
+	for _, id := range r.PostForm["calendar"] {
+		c := config.CalendarSelectionConfig{
+			Id:            id,
+			Remind:        r.PostForm.Get("remind:"+id) != "",
+			NotifyChanges: r.PostForm.Get("changes:"+id) != "",
+		}
+
+		if c.Remind || c.NotifyChanges {
+			selected = append(selected, c)
+		}
+	}
+
+	prev := cfg.Calendar.Calendars
+	cfg.Calendar.Calendars = selected
+
+	err = config.Save(cfg)
+	if err != nil {
+		cfg.Calendar.Calendars = prev
+		return err
+	}
+
+	sel := make([]gworkspace.CalendarSelection, len(selected))
+	for i, c := range selected {
+		sel[i] = gworkspace.CalendarSelection{Id: c.Id, Remind: c.Remind, NotifyChanges: c.NotifyChanges}
+	}
+
+	return cal.SetCalendars(sel)
 }
@@ -0,0 +1,135 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// CsrfField is the name of the form field that carries the csrf token
+const CsrfField = "csrf"
+
+// how long a token minted for a page stays valid
+const csrfTokenTtl = time.Hour * 12
+
+type response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// WriteResponse answers a form submission. fetch requests that accept json get
+// the error, if any, as json. plain form submissions are redirected back to
+// the index page, or get the error as text
+func WriteResponse(w http.ResponseWriter, r *http.Request, status int, errMsg string) {
+	if r.Header.Get("Accept") != "application/json" {
+		if errMsg != "" {
+			http.Error(w, errMsg, status)
+		} else {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+		}
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response{Error: errMsg})
+}
+
+// Tokens are csrf tokens minted for rendered pages. a form that changes
+// something has to send back a token from a page that this server rendered,
+// which another site cannot read
+type Tokens struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+func NewTokens() *Tokens {
+	return &Tokens{tokens: make(map[string]time.Time)}
+}
+
+// Mint creates a new token
+func (t *Tokens) Mint() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for tok, expires := range t.tokens {
+		if now.After(expires) {
+			delete(t.tokens, tok)
+		}
+	}
+
+	t.tokens[token] = now.Add(csrfTokenTtl)
+
+	return token
+}
+
+// Valid reports whether a token was minted and has not expired. the token can
+// be used again, e.g. for every action on a page
+func (t *Tokens) Valid(token string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	expires, ok := t.tokens[token]
+	return ok && time.Now().Before(expires)
+}
+
+// Consume reports whether a token is valid and makes sure it cannot be used
+// again
+func (t *Tokens) Consume(token string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	expires, ok := t.tokens[token]
+	delete(t.tokens, token)
+
+	return ok && time.Now().Before(expires)
+}
+
+// SameOrigin reports whether a request was made by a page of this server.
+// browsers send Sec-Fetch-Site or Origin with every post, requests without
+// either did not come from a browser
+func SameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// Guard rejects requests that came from another site or do not carry a valid
+// token before passing them on to next
+func Guard(tokens *Tokens, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !SameOrigin(r) {
+			WriteResponse(w, r, http.StatusForbidden, "cross-origin request")
+			return
+		}
+
+		if !tokens.Valid(r.FormValue(CsrfField)) {
+			WriteResponse(w, r, http.StatusForbidden, "invalid or expired csrf token, reload the page")
+			return
+		}
+
+		next(w, r)
+	}
+}
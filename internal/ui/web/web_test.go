@@ -0,0 +1,103 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/link00000000/gwsn/internal/ui/web"
+)
+
+func TestTokens(t *testing.T) {
+	tokens := web.NewTokens()
+
+	if tokens.Valid("") || tokens.Valid("made up") {
+		t.Fatal("expected tokens that were not minted to be invalid")
+	}
+
+	token := tokens.Mint()
+	if !tokens.Valid(token) || !tokens.Valid(token) {
+		t.Fatal("expected a minted token to stay valid")
+	}
+
+	if !tokens.Consume(token) {
+		t.Fatal("expected a minted token to be consumed")
+	}
+
+	if tokens.Valid(token) || tokens.Consume(token) {
+		t.Fatal("expected a consumed token to be invalid")
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected bool
+	}{
+		{name: "no headers", expected: true},
+		{name: "same site fetch", headers: map[string]string{"Sec-Fetch-Site": "same-origin"}, expected: true},
+		{name: "typed by user", headers: map[string]string{"Sec-Fetch-Site": "none"}, expected: true},
+		{name: "cross site fetch", headers: map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "http://127.0.0.1:8080"}, expected: false},
+		{name: "same site of other port", headers: map[string]string{"Sec-Fetch-Site": "same-site"}, expected: false},
+		{name: "same origin", headers: map[string]string{"Origin": "http://127.0.0.1:8080"}, expected: true},
+		{name: "other origin", headers: map[string]string{"Origin": "https://evil.example.com"}, expected: false},
+		{name: "opaque origin", headers: map[string]string{"Origin": "null"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/gmail/action", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			if got := web.SameOrigin(r); got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	tokens := web.NewTokens()
+	token := tokens.Mint()
+
+	called := 0
+	h := web.Guard(tokens, func(w http.ResponseWriter, r *http.Request) { called++ })
+
+	post := func(form url.Values, origin string) int {
+		r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1:8080/gmail/action", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Accept", "application/json")
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+
+		w := httptest.NewRecorder()
+		h(w, r)
+
+		return w.Code
+	}
+
+	if code := post(url.Values{"id": {"1"}}, ""); code != http.StatusForbidden {
+		t.Fatalf("expected a request without a token to be forbidden, got %d", code)
+	}
+
+	if code := post(url.Values{"id": {"1"}, web.CsrfField: {token}}, "https://evil.example.com"); code != http.StatusForbidden {
+		t.Fatalf("expected a cross-origin request to be forbidden, got %d", code)
+	}
+
+	if called != 0 {
+		t.Fatalf("expected rejected requests to not reach the handler, got %d calls", called)
+	}
+
+	post(url.Values{"id": {"1"}, web.CsrfField: {token}}, "http://127.0.0.1:8080")
+	post(url.Values{"id": {"2"}, web.CsrfField: {token}}, "")
+
+	if called != 2 {
+		t.Fatalf("expected valid requests to reach the handler, got %d calls", called)
+	}
+}
@@ -8,16 +8,46 @@ import (
 	"os"
 	"time"
 
+	"github.com/link00000000/gwsn/internal/config"
+	"github.com/link00000000/gwsn/internal/dnd"
 	"github.com/link00000000/gwsn/internal/gworkspace"
+	"github.com/link00000000/gwsn/internal/history"
+	"github.com/link00000000/gwsn/internal/notify"
+	"github.com/link00000000/gwsn/internal/status"
+	"github.com/link00000000/gwsn/internal/sysevents"
 	"github.com/link00000000/gwsn/internal/sysnotif"
 	"github.com/link00000000/gwsn/internal/systray"
 	"github.com/link00000000/gwsn/internal/ui"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/alertcenter/v1beta1"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/chat/v1"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/gmail/v1"
 	"google.golang.org/api/option"
+	"google.golang.org/api/people/v1"
+	"google.golang.org/api/tasks/v1"
 )
 
-func RunSystray(ctx context.Context, cancel context.CancelFunc) error {
+// the web ui can act on the account, so it is only served to this machine
+const httpServerAddr = "127.0.0.1:8080"
+
+// how often the quota usage shown in the status is updated
+const quotaUsageRefreshFreq = time.Second * 10
+
+// how long to wait for more resume and network events before checking, since
+// they come in bursts
+const sysEventSettleTime = time.Second * 2
+
+// how long to wait for connectivity after a resume or network change before
+// leaving it to the next scheduled check
+const sysEventConnectivityTimeout = time.Minute * 2
+
+// how often the next meeting and do not disturb are recomputed, so that they
+// follow the meetings as they start and end
+const calendarStatusRefreshFreq = time.Second * 30
+
+func RunSystray(ctx context.Context, cancel context.CancelFunc, hist *history.History, st *status.Status, acts *gworkspace.GmailActions, snoozer *gworkspace.GmailSnoozer) error {
 	s := systray.NewSystray()
 	s.Start()
 
@@ -26,6 +56,19 @@ loop:
 		select {
 		case <-s.ExitReq():
 			cancel()
+		case req := <-s.MessageActionReq():
+			go notify.ApplyGmailAction(ctx, hist, acts, req.Id, req.Action)
+		case req := <-s.SnoozeReq():
+			notify.SnoozeMessage(hist, snoozer, req.Id, req.Option)
+		case link := <-s.JoinReq():
+			notify.JoinMeeting(link)
+		case <-hist.Changed():
+			s.SetRecentMessages(recentMessages(hist))
+		case <-st.Changed():
+			s.SetUnreadCounts(st.UnreadCounts())
+			s.SetOffline(st.Offline())
+			s.SetNextMeeting(st.NextEvent())
+			s.SetDoNotDisturb(st.DndReason())
 		case <-ctx.Done():
 			break loop
 		}
@@ -38,68 +81,450 @@ loop:
 	return nil
 }
 
-func RunMonitor(ctx context.Context) error {
-	/*
-		m, err := gworkspace.NewMonitor()
+func RunMonitor(ctx context.Context, cfg *config.Config, svc *gmail.Service, peopleSvc *people.Service, quota *gworkspace.GmailQuota, hist *history.History, st *status.Status, acts *gworkspace.GmailActions, snoozer *gworkspace.GmailSnoozer, quiet *dnd.Dnd) error {
+	m := gworkspace.NewGmailMonitor(svc, gworkspace.GmailMonitorCfg{
+		UpdateFreq:        time.Duration(cfg.Gmail.UpdateFreq),
+		FetchAttachments:  cfg.Gmail.FetchAttachments,
+		Labels:            cfg.Gmail.Labels,
+		UnreadRefreshFreq: time.Duration(cfg.Gmail.UnreadRefreshFreq),
+		Aliases:           aliasAddresses(cfg.Gmail.Aliases),
+		OfflineAfter:      cfg.Gmail.OfflineAfter,
+		Quota:             quota,
+	})
+
+	for {
+		err := m.Initialize(ctx)
+		if err == nil {
+			break
+		}
+
+		if !gworkspace.IsNetworkError(err) {
+			return fmt.Errorf("error while initializing gmail monitor: %v", err)
+		}
+
+		slog.Warn("gmail is unreachable, waiting for connectivity before initializing", "error", err)
+		st.SetOffline(true)
+
+		err = gworkspace.WaitForConnectivity(ctx)
+		if err != nil {
+			return nil
+		}
+	}
+
+	st.SetOffline(false)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	var followUpReminders <-chan []*gworkspace.GmailFollowUp
+	if cfg.Gmail.FollowUps.Enabled {
+		t, err := gworkspace.NewGmailFollowUpTracker(svc, gworkspace.GmailFollowUpTrackerCfg{
+			After:   time.Duration(cfg.Gmail.FollowUps.After),
+			Label:   cfg.Gmail.FollowUps.Label,
+			Keyword: cfg.Gmail.FollowUps.Keyword,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to create monitor: %v", err)
+			return fmt.Errorf("error while creating follow up tracker: %v", err)
 		}
 
-		go m.Run() // TODO: Handle error and early terminate
-		defer m.Stop()
+		m.AddHistoryHandler(t)
+		followUpReminders = t.Reminders()
+
+		g.Go(func() error {
+			return t.Watch(ctx)
+		})
+	}
+
+	vips, err := gworkspace.NewGmailVips(peopleSvc, gworkspace.GmailVipCfg{
+		Addresses:         cfg.Gmail.Vip.Addresses,
+		Domains:           cfg.Gmail.Vip.Domains,
+		ContactGroups:     cfg.Gmail.Vip.ContactGroups,
+		RenotifyIntervals: durations(cfg.Gmail.Vip.RenotifyIntervals),
+		MaxRenotify:       cfg.Gmail.Vip.MaxRenotify,
+	})
+	if err != nil {
+		return fmt.Errorf("error while creating vip list: %v", err)
+	}
+
+	m.AddHistoryHandler(vips)
+
+	g.Go(func() error {
+		return snoozer.Watch(ctx)
+	})
+
+	g.Go(func() error {
+		ticker := time.NewTicker(quotaUsageRefreshFreq)
+		defer ticker.Stop()
 
 		for {
+			st.SetQuotaUsage(quota.Usage())
+
 			select {
-			case <-m.CalendarReminders():
-				slog.Info("recieved calendar reminder") // TODO: add attrs
-				sysnotif.ShowNotification("Upcoming calendar event", "There is an upcoming calendar event")
-				// TODO: notify new calendar reminder
-			case <-m.Email():
-				slog.Info("recieved email") // TODO: add attrs
-				sysnotif.ShowNotification("New email", "There is a new email")
-				// TODO: notify new email
+			case <-ticker.C:
 			case <-ctx.Done():
 				return nil
 			}
 		}
-	*/
+	})
 
-	httpClient := gworkspace.NewHttpClient()
-	err := httpClient.Configure(ctx, gmail.GmailReadonlyScope)
-	if err != nil {
-		return fmt.Errorf("error while configuring http client: %v", err)
-	}
+	g.Go(func() error {
+		return vips.Watch(ctx)
+	})
 
-	svc, err := gmail.NewService(ctx, option.WithHTTPClient(httpClient.Client))
-	if err != nil {
-		return fmt.Errorf("error while creating gmail service: %v", err)
-	}
+	g.Go(func() error {
+		for {
+			select {
+			case msgs := <-m.Messages():
+				for _, msg := range msgs {
+					if acts.IsThreadMuted(msg.ThreadId) {
+						slog.Debug("archiving new message in muted thread", "messageId", msg.Id, "threadId", msg.ThreadId)
+
+						err := acts.ApplyToMessage(ctx, gworkspace.GmailAction_Archive, msg)
+						if err != nil {
+							slog.Error("failed to archive message in muted thread", "error", err)
+						}
+
+						continue
+					}
+
+					alias := cfg.Gmail.Alias(msg.Alias)
+
+					title := "New message from " + msg.From
+					if msg.Alias != "" {
+						name := alias.Name
+						if name == "" {
+							name = msg.Alias
+						}
+
+						title = "New message to " + name + " from " + msg.From
+					}
+
+					body := msg.Subject
+					if summary := msg.AttachmentSummary(); summary != "" {
+						body += "\nAttachments: " + summary
+					}
+
+					e := hist.Add(title, body, msg)
+
+					if alias.Mute {
+						slog.Debug("not showing notification for muted alias", "alias", msg.Alias, "messageId", msg.Id)
+						continue
+					}
+
+					urgency := sysnotif.Urgency_Normal
+					if vips.IsVip(msg.From) {
+						urgency = sysnotif.Urgency_Critical
+						vips.Track(msg)
+					}
+
+					// vips get through do not disturb
+					if urgency != sysnotif.Urgency_Critical && quiet.Defer(title, body) {
+						slog.Debug("deferring notification while do not disturb is on", "messageId", msg.Id)
+						continue
+					}
+
+					notify.ShowMessage(ctx, hist, acts, snoozer, e.Id, title, body, urgency)
+				}
+			case renotify := <-vips.Renotify():
+				for _, r := range renotify {
+					title := fmt.Sprintf("Unread message from %s (reminder %d of %d)", r.Message.From, r.Count, cfg.Gmail.Vip.MaxRenotify)
+
+					// messages tracked before a restart are not in the
+					// history yet. they are still unread, otherwise the vip
+					// list would have stopped tracking them
+					e, ok := hist.FindMessage(r.Message.Id)
+					if !ok {
+						e = hist.Add(title, r.Message.Subject, r.Message)
+					} else if !e.HasLabel(gworkspace.GmailLabel_Unread) {
+						continue
+					}
+
+					notify.ShowMessage(ctx, hist, acts, snoozer, e.Id, title, r.Message.Subject, sysnotif.Urgency_Critical)
+				}
+			case woken := <-snoozer.Wake():
+				for _, z := range woken {
+					// the entry may have been dropped from the history while
+					// the message was snoozed
+					e, ok := hist.FindMessage(z.Message.Id)
+					if !ok {
+						e = hist.Add(z.Title, z.Body, z.Message)
+					}
+
+					if quiet.Defer(z.Title, z.Body) {
+						continue
+					}
+
+					notify.ShowMessage(ctx, hist, acts, snoozer, e.Id, z.Title, z.Body, sysnotif.Urgency_Normal)
+				}
+			case followUps := <-followUpReminders:
+				for _, f := range followUps {
+					title := "No reply to \"" + f.Subject + "\""
+					body := "Sent to " + f.To + " on " + f.SentAt.Format("Mon Jan 2 15:04")
+
+					hist.Add(title, body, nil)
+					if !quiet.Defer(title, body) {
+						sysnotif.ShowNotification(title, body)
+					}
+				}
+			case counts := <-m.UnreadCounts():
+				st.SetUnreadCounts(counts)
+			case online := <-m.Online():
+				st.SetOffline(!online)
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
 
-	m := gworkspace.NewGmailMonitor(svc, time.Minute*1)
+	g.Go(func() error {
+		return checkOnSysEvents(ctx, m)
+	})
 
-	err = m.Initialize(ctx)
-	if err != nil {
-		return fmt.Errorf("error while initializing gmail monitor: %v", err)
+	g.Go(func() error {
+		err := m.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("error while watching gmail monitor: %v", err)
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
+
+// checkOnSysEvents checks for new messages as soon as the google apis can be
+// reached after the system resumes or its network changes, instead of waiting
+// for the next scheduled check
+func checkOnSysEvents(ctx context.Context, m *gworkspace.GmailMonitor) error {
+	w := sysevents.NewWatcher(sysevents.WatcherCfg{
+		ClockCheckFreq:     sysevents.DefaultClockCheckFreq,
+		ClockJumpThreshold: sysevents.DefaultClockJumpThreshold,
+	})
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		return w.Watch(ctx)
+	})
+
+	g.Go(func() error {
+		for {
+			select {
+			case e := <-w.Events():
+				slog.Info("system event, checking for new messages once online", "event", e)
+			case <-ctx.Done():
+				return nil
+			}
+
+			settle := time.After(sysEventSettleTime)
+		settling:
+			for {
+				select {
+				case <-w.Events():
+				case <-settle:
+					break settling
+				case <-ctx.Done():
+					return nil
+				}
+			}
+
+			waitCtx, cancel := context.WithTimeout(ctx, sysEventConnectivityTimeout)
+			err := gworkspace.WaitForConnectivity(waitCtx)
+			cancel()
+
+			if err != nil {
+				slog.Warn("google apis are still not reachable, waiting for the next scheduled check", "error", err)
+				continue
+			}
+
+			if m.OverQuota() {
+				slog.Warn("gmail quota budget reached, waiting for the next scheduled check")
+				continue
+			}
+
+			err = m.CheckNow(ctx)
+			if err != nil {
+				slog.Error("error while checking for new messages", "error", err)
+			}
+		}
+	})
+
+	return g.Wait()
+}
+
+func RunCalendarMonitor(ctx context.Context, m *gworkspace.CalendarMonitor, hist *history.History, st *status.Status, quiet *dnd.Dnd) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	updateStatus := func() {
+		now := time.Now()
+		st.SetNextEvent(m.NextEvent(now))
+
+		var reason string
+		if d := m.DoNotDisturb(now); d != nil {
+			reason = d.Reason
+		}
+
+		if prev := quiet.Reason(); prev != reason {
+			slog.Info("do not disturb changed", "reason", reason, "previousReason", prev)
+		}
+
+		released := quiet.Set(reason)
+		st.SetDndReason(reason)
+
+		if len(released) > 0 {
+			title, body := notify.DeferredSummary(released)
+			sysnotif.ShowNotification(title, body)
+		}
 	}
 
+	g.Go(func() error {
+		ticker := time.NewTicker(calendarStatusRefreshFreq)
+		defer ticker.Stop()
+
+		updateStatus()
+
+		for {
+			select {
+			case reminders := <-m.Reminders():
+				for _, r := range reminders {
+					title, body := notify.CalendarReminder(r.Event)
+
+					hist.AddEvent(title, body, r.Event)
+					notify.ShowReminder(r.Event, title, body)
+				}
+			case changes := <-m.Changes():
+				for _, c := range changes {
+					slog.Info("calendar event changed", "kind", c.Kind, "calendarId", c.Event.CalendarId, "eventId", c.Event.Id, "summary", c.Event.Summary)
+
+					title, body, ok := notify.CalendarChange(c)
+					if !ok {
+						continue
+					}
+
+					if c.Kind == gworkspace.CalendarChangeKind_Invited {
+						e := hist.AddEvent(title, body, c.Event)
+						if !quiet.Defer(title, body) {
+							notify.ShowInvitation(ctx, hist, m, e.Id, title, body)
+						}
+
+						continue
+					}
+
+					hist.Add(title, body, nil)
+					if !quiet.Defer(title, body) {
+						sysnotif.ShowNotification(title, body)
+					}
+				}
+
+				updateStatus()
+			case conflicts := <-m.Conflicts():
+				for _, c := range conflicts {
+					slog.Info("calendar conflict", "eventId", c.A.Id, "conflictingEventId", c.B.Id)
+
+					title, body := notify.CalendarConflict(c, m.CalendarName)
+
+					hist.Add(title, body, nil)
+					if !quiet.Defer(title, body) {
+						notify.ShowConflict(c, title, body)
+					}
+				}
+			case agenda := <-m.Digests():
+				title, body := notify.CalendarDigest(agenda, m.Location())
+
+				hist.Add(title, body, nil)
+				sysnotif.ShowNotification(title, body)
+			case <-ticker.C:
+				updateStatus()
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	g.Go(func() error {
+		err := m.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("error while watching calendar monitor: %v", err)
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
+
+func RunChatMonitor(ctx context.Context, m *gworkspace.ChatMonitor, hist *history.History, quiet *dnd.Dnd) error {
 	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
-		select {
-		case msgs := <-m.Messages():
-			for _, msg := range msgs {
-				sysnotif.ShowNotification("New message from "+msg.From, msg.Subject)
+		for {
+			select {
+			case convs := <-m.Conversations():
+				for _, c := range convs {
+					slog.Info("new chat messages", "space", c.Space.Name, "count", len(c.Messages))
+
+					title, body := notify.Chat(c)
+
+					hist.Add(title, body, nil)
+					if !quiet.Defer(title, body) {
+						notify.ShowLink(title, body, c.Space.Uri)
+					}
+				}
+			case <-ctx.Done():
+				return nil
 			}
-		case <-ctx.Done():
+		}
+	})
+
+	g.Go(func() error {
+		err := m.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("error while watching chat monitor: %v", err)
 		}
 
 		return nil
 	})
 
+	return g.Wait()
+}
+
+func RunDriveMonitor(ctx context.Context, m *gworkspace.DriveMonitor, hist *history.History, quiet *dnd.Dnd) error {
+	g, ctx := errgroup.WithContext(ctx)
+
 	g.Go(func() error {
-		err = m.Watch(ctx)
+		for {
+			select {
+			case files := <-m.Shared():
+				for _, f := range files {
+					slog.Info("file shared with user", "fileId", f.Id, "name", f.Name, "mimeType", f.MimeType)
+
+					title, body := notify.DriveShared(f)
+
+					hist.Add(title, body, nil)
+					if !quiet.Defer(title, body) {
+						notify.ShowLink(title, body, f.WebViewLink)
+					}
+				}
+			case comments := <-m.Comments():
+				for _, c := range comments {
+					slog.Info("drive comment for user", "fileId", c.FileId, "commentId", c.CommentId, "replyId", c.ReplyId, "assigned", c.Assigned)
+
+					title, body := notify.DriveComment(c)
+
+					hist.Add(title, body, nil)
+					if !quiet.Defer(title, body) {
+						notify.ShowLink(title, body, c.Link)
+					}
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	g.Go(func() error {
+		err := m.Watch(ctx)
 		if err != nil {
-			return fmt.Errorf("error while watching gmail monitor: %v", err)
+			return fmt.Errorf("error while watching drive monitor: %v", err)
 		}
 
 		return nil
@@ -108,8 +533,84 @@ func RunMonitor(ctx context.Context) error {
 	return g.Wait()
 }
 
-func RunHttpServer(ctx context.Context) error {
-	s := &http.Server{Addr: ":8080", Handler: ui.NewHandler()}
+func RunTasksMonitor(ctx context.Context, m *gworkspace.TasksMonitor, hist *history.History, quiet *dnd.Dnd) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		for {
+			select {
+			case reminders := <-m.Reminders():
+				for _, r := range reminders {
+					title, body := notify.TaskReminder(r)
+
+					hist.Add(title, body, nil)
+
+					// reminders before the due time are as urgent as calendar
+					// reminders, the daily overdue reminders can wait
+					if r.Overdue && quiet.Defer(title, body) {
+						continue
+					}
+
+					notify.ShowTask(ctx, m, r.Task, title, body)
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	g.Go(func() error {
+		err := m.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("error while watching tasks monitor: %v", err)
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
+
+func RunAlertsMonitor(ctx context.Context, m *gworkspace.AlertsMonitor, hist *history.History, quiet *dnd.Dnd) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		for {
+			select {
+			case alerts := <-m.Alerts():
+				for _, a := range alerts {
+					slog.Info("new workspace alert", "alertId", a.Id, "type", a.Type, "severity", a.Severity)
+
+					title, body := notify.Alert(a)
+					urgency := notify.AlertUrgency(a.Severity)
+
+					hist.Add(title, body, nil)
+					if urgency != sysnotif.Urgency_Critical && quiet.Defer(title, body) {
+						continue
+					}
+
+					notify.ShowAlert(a, title, body, urgency)
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+
+	g.Go(func() error {
+		err := m.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("error while watching alerts monitor: %v", err)
+		}
+
+		return nil
+	})
+
+	return g.Wait()
+}
+
+func RunHttpServer(ctx context.Context, cfg *config.Config, hist *history.History, st *status.Status, acts *gworkspace.GmailActions, cal *gworkspace.CalendarMonitor) error {
+	s := &http.Server{Addr: httpServerAddr, Handler: ui.NewHandler(cfg, hist, st, acts, cal)}
 
 	go s.ListenAndServe()
 	<-ctx.Done()
@@ -117,16 +618,251 @@ func RunHttpServer(ctx context.Context) error {
 	return s.Shutdown(context.TODO())
 }
 
+func calendarSelections(cals []config.CalendarSelectionConfig) []gworkspace.CalendarSelection {
+	sel := make([]gworkspace.CalendarSelection, len(cals))
+	for i, c := range cals {
+		sel[i] = gworkspace.CalendarSelection{Id: c.Id, Remind: c.Remind, NotifyChanges: c.NotifyChanges}
+	}
+
+	return sel
+}
+
+func calendarDndCfg(c config.CalendarDndConfig) gworkspace.CalendarDndCfg {
+	if !c.Enabled {
+		return gworkspace.CalendarDndCfg{}
+	}
+
+	cfg := gworkspace.CalendarDndCfg{FocusTime: c.FocusTime, OutOfOffice: c.OutOfOffice}
+	for _, r := range c.Rules {
+		cfg.Rules = append(cfg.Rules, gworkspace.CalendarDndRule{Keyword: r.Keyword, MinAttendees: r.MinAttendees, BusyOnly: r.BusyOnly})
+	}
+
+	return cfg
+}
+
+func durations(ds []config.Duration) []time.Duration {
+	converted := make([]time.Duration, len(ds))
+	for i, d := range ds {
+		converted[i] = time.Duration(d)
+	}
+
+	return converted
+}
+
+func quotaWindows(budgets []config.QuotaWindowConfig) []gworkspace.GmailQuotaWindow {
+	windows := make([]gworkspace.GmailQuotaWindow, len(budgets))
+	for i, b := range budgets {
+		windows[i] = gworkspace.GmailQuotaWindow{Duration: time.Duration(b.Duration), Budget: b.Budget}
+	}
+
+	return windows
+}
+
+func aliasAddresses(aliases []config.AliasConfig) []string {
+	addrs := make([]string, len(aliases))
+	for i, a := range aliases {
+		addrs[i] = a.Address
+	}
+
+	return addrs
+}
+
+// recentMessages returns the messages from the history that are still in the
+// inbox, newest first
+func recentMessages(hist *history.History) []systray.RecentMessage {
+	msgs := make([]systray.RecentMessage, 0)
+	for _, e := range hist.Entries() {
+		if e.Message == nil || !e.HasLabel(gworkspace.GmailLabel_Inbox) {
+			continue
+		}
+
+		msgs = append(msgs, systray.RecentMessage{
+			Id:     e.Id,
+			Title:  e.Message.From + ": " + e.Message.Subject,
+			Labels: e.Labels,
+		})
+	}
+
+	return msgs
+}
+
 func main() {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})))
 
+	cfg, err := config.Load()
+	if err != nil {
+		panic(fmt.Errorf("failed to load config: %v", err))
+	}
+
+	hist := history.NewHistory(100)
+	st := status.NewStatus()
+
 	ctx, cancel := context.WithCancel(context.Background())
+
+	quota := gworkspace.NewGmailQuota(quotaWindows(cfg.Gmail.QuotaBudgets))
+
+	httpClient := gworkspace.NewHttpClient(quota)
+	scopes := []string{gmail.GmailModifyScope, gmail.GmailSendScope}
+	if cfg.Calendar.Enabled {
+		// events scope to respond to invitations, readonly for the calendar
+		// list
+		scopes = append(scopes, calendar.CalendarEventsScope, calendar.CalendarReadonlyScope)
+	}
+
+	if len(cfg.Gmail.Vip.ContactGroups) > 0 {
+		scopes = append(scopes, people.ContactsReadonlyScope)
+	}
+
+	if cfg.Chat.Enabled {
+		// profile to find out who the user is, directory for the names of
+		// senders
+		scopes = append(scopes, chat.ChatSpacesReadonlyScope, chat.ChatMessagesReadonlyScope, people.UserinfoProfileScope, people.DirectoryReadonlyScope)
+	}
+
+	if cfg.Drive.Enabled && cfg.Drive.SharedWithMe {
+		scopes = append(scopes, drive.DriveMetadataReadonlyScope)
+	}
+
+	if cfg.Drive.Enabled && cfg.Drive.Comments {
+		// comments cannot be read with the metadata scope
+		scopes = append(scopes, drive.DriveReadonlyScope)
+	}
+
+	if cfg.Tasks.Enabled {
+		// read and write, to mark tasks as complete
+		scopes = append(scopes, tasks.TasksScope)
+	}
+
+	alertCenterEnabled := cfg.Admin && cfg.AlertCenter.Enabled
+	if alertCenterEnabled {
+		scopes = append(scopes, alertcenter.AppsAlertsScope)
+	}
+
+	err = httpClient.Configure(ctx, scopes...)
+	if err != nil {
+		panic(fmt.Errorf("error while configuring http client: %v", err))
+	}
+
+	svc, err := gmail.NewService(ctx, option.WithHTTPClient(httpClient.Client))
+	if err != nil {
+		panic(fmt.Errorf("error while creating gmail service: %v", err))
+	}
+
+	peopleSvc, err := people.NewService(ctx, option.WithHTTPClient(httpClient.Client))
+	if err != nil {
+		panic(fmt.Errorf("error while creating people service: %v", err))
+	}
+
+	calendarSvc, err := calendar.NewService(ctx, option.WithHTTPClient(httpClient.Client))
+	if err != nil {
+		panic(fmt.Errorf("error while creating calendar service: %v", err))
+	}
+
+	acts, err := gworkspace.NewGmailActions(svc)
+	if err != nil {
+		panic(fmt.Errorf("error while creating gmail actions: %v", err))
+	}
+
+	snoozer, err := gworkspace.NewGmailSnoozer()
+	if err != nil {
+		panic(fmt.Errorf("error while creating snoozer: %v", err))
+	}
+
+	quiet := dnd.New()
+
+	var cal *gworkspace.CalendarMonitor
+	if cfg.Calendar.Enabled {
+		cal, err = gworkspace.NewCalendarMonitor(calendarSvc, gworkspace.CalendarMonitorCfg{
+			UpdateFreq: time.Duration(cfg.Calendar.UpdateFreq),
+			Calendars:  calendarSelections(cfg.Calendar.Calendars),
+			LookAhead:  time.Duration(cfg.Calendar.LookAhead),
+			Digest: gworkspace.CalendarDigestCfg{
+				Enabled:      cfg.Calendar.Digest.Enabled,
+				At:           time.Duration(cfg.Calendar.Digest.At),
+				SkipEmpty:    cfg.Calendar.Digest.SkipEmpty,
+				WorkdayStart: time.Duration(cfg.Calendar.Digest.WorkdayStart),
+				WorkdayEnd:   time.Duration(cfg.Calendar.Digest.WorkdayEnd),
+			},
+			Dnd: calendarDndCfg(cfg.Calendar.Dnd),
+		})
+		if err != nil {
+			panic(fmt.Errorf("error while creating calendar monitor: %v", err))
+		}
+	}
+
+	var chatMon *gworkspace.ChatMonitor
+	if cfg.Chat.Enabled {
+		chatSvc, err := chat.NewService(ctx, option.WithHTTPClient(httpClient.Client))
+		if err != nil {
+			panic(fmt.Errorf("error while creating chat service: %v", err))
+		}
+
+		chatMon, err = gworkspace.NewChatMonitor(chatSvc, peopleSvc, gworkspace.ChatMonitorCfg{
+			UpdateFreq:     time.Duration(cfg.Chat.UpdateFreq),
+			MutedSpaces:    cfg.Chat.MutedSpaces,
+			CoalesceWindow: time.Duration(cfg.Chat.CoalesceWindow),
+		})
+		if err != nil {
+			panic(fmt.Errorf("error while creating chat monitor: %v", err))
+		}
+	}
+
+	var driveMon *gworkspace.DriveMonitor
+	if cfg.Drive.Enabled {
+		driveSvc, err := drive.NewService(ctx, option.WithHTTPClient(httpClient.Client))
+		if err != nil {
+			panic(fmt.Errorf("error while creating drive service: %v", err))
+		}
+
+		driveMon, err = gworkspace.NewDriveMonitor(driveSvc, gworkspace.DriveMonitorCfg{
+			UpdateFreq:   time.Duration(cfg.Drive.UpdateFreq),
+			SharedWithMe: cfg.Drive.SharedWithMe,
+			Comments:     cfg.Drive.Comments,
+		})
+		if err != nil {
+			panic(fmt.Errorf("error while creating drive monitor: %v", err))
+		}
+	}
+
+	var tasksMon *gworkspace.TasksMonitor
+	if cfg.Tasks.Enabled {
+		tasksSvc, err := tasks.NewService(ctx, option.WithHTTPClient(httpClient.Client))
+		if err != nil {
+			panic(fmt.Errorf("error while creating tasks service: %v", err))
+		}
+
+		tasksMon, err = gworkspace.NewTasksMonitor(tasksSvc, gworkspace.TasksMonitorCfg{
+			UpdateFreq: time.Duration(cfg.Tasks.UpdateFreq),
+			DueAt:      time.Duration(cfg.Tasks.DueAt),
+			Offsets:    durations(cfg.Tasks.Offsets),
+			OverdueAt:  time.Duration(cfg.Tasks.OverdueAt),
+		})
+		if err != nil {
+			panic(fmt.Errorf("error while creating tasks monitor: %v", err))
+		}
+	}
+
+	var alertsMon *gworkspace.AlertsMonitor
+	if alertCenterEnabled {
+		alertsSvc, err := alertcenter.NewService(ctx, option.WithHTTPClient(httpClient.Client))
+		if err != nil {
+			panic(fmt.Errorf("error while creating alert center service: %v", err))
+		}
+
+		alertsMon, err = gworkspace.NewAlertsMonitor(alertsSvc, gworkspace.AlertsMonitorCfg{
+			UpdateFreq: time.Duration(cfg.AlertCenter.UpdateFreq),
+		})
+		if err != nil {
+			panic(fmt.Errorf("error while creating alerts monitor: %v", err))
+		}
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 
 	g.Go(func() error {
 		slog.Info("starting systray")
 
-		err := RunSystray(ctx, cancel)
+		err := RunSystray(ctx, cancel, hist, st, acts, snoozer)
 		if err != nil {
 			panic(fmt.Errorf("RunSystray completed with unhandled error: %v", err))
 		}
@@ -139,7 +875,7 @@ func main() {
 	g.Go(func() error {
 		slog.Info("starting RunHttpServer")
 
-		err := RunHttpServer(ctx)
+		err := RunHttpServer(ctx, cfg, hist, st, acts, cal)
 		if err != nil {
 			panic(fmt.Errorf("RunHttpServer completed with unhandled error: %v", err))
 		}
@@ -152,7 +888,7 @@ func main() {
 	g.Go(func() error {
 		slog.Info("starting RunMonitor")
 
-		err := RunMonitor(ctx)
+		err := RunMonitor(ctx, cfg, svc, peopleSvc, quota, hist, st, acts, snoozer, quiet)
 		if err != nil {
 			panic(fmt.Errorf("RunMonitor completed with unhandled error: %v", err))
 		}
@@ -162,6 +898,81 @@ func main() {
 		return nil
 	})
 
+	if cal != nil {
+		g.Go(func() error {
+			slog.Info("starting RunCalendarMonitor")
+
+			err := RunCalendarMonitor(ctx, cal, hist, st, quiet)
+			if err != nil {
+				panic(fmt.Errorf("RunCalendarMonitor completed with unhandled error: %v", err))
+			}
+
+			slog.Info("RunCalendarMonitor completed without error")
+
+			return nil
+		})
+	}
+
+	if chatMon != nil {
+		g.Go(func() error {
+			slog.Info("starting RunChatMonitor")
+
+			err := RunChatMonitor(ctx, chatMon, hist, quiet)
+			if err != nil {
+				panic(fmt.Errorf("RunChatMonitor completed with unhandled error: %v", err))
+			}
+
+			slog.Info("RunChatMonitor completed without error")
+
+			return nil
+		})
+	}
+
+	if driveMon != nil {
+		g.Go(func() error {
+			slog.Info("starting RunDriveMonitor")
+
+			err := RunDriveMonitor(ctx, driveMon, hist, quiet)
+			if err != nil {
+				panic(fmt.Errorf("RunDriveMonitor completed with unhandled error: %v", err))
+			}
+
+			slog.Info("RunDriveMonitor completed without error")
+
+			return nil
+		})
+	}
+
+	if tasksMon != nil {
+		g.Go(func() error {
+			slog.Info("starting RunTasksMonitor")
+
+			err := RunTasksMonitor(ctx, tasksMon, hist, quiet)
+			if err != nil {
+				panic(fmt.Errorf("RunTasksMonitor completed with unhandled error: %v", err))
+			}
+
+			slog.Info("RunTasksMonitor completed without error")
+
+			return nil
+		})
+	}
+
+	if alertsMon != nil {
+		g.Go(func() error {
+			slog.Info("starting RunAlertsMonitor")
+
+			err := RunAlertsMonitor(ctx, alertsMon, hist, quiet)
+			if err != nil {
+				panic(fmt.Errorf("RunAlertsMonitor completed with unhandled error: %v", err))
+			}
+
+			slog.Info("RunAlertsMonitor completed without error")
+
+			return nil
+		})
+	}
+
 	if err := g.Wait(); err != nil {
 		panic(err)
 	}